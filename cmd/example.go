@@ -45,7 +45,7 @@ func initOtel() *otelBuilder.Otel {
 
 	if err != nil {
 		l.Error("Failed to initialize OpenTelemetry", err)
-		return nil
+		return otelBuilder.NewNoop()
 	}
 
 	return otelBuilder.NewOtel(tracing, metrics, l)
@@ -86,7 +86,7 @@ func main() {
 }
 
 func testFunc(ctx context.Context) {
-	ctx, span := otelConfig.Tracing.GetTracer().Start(ctx, "Test Func 1")
+	ctx, span := otelConfig.Tracing.StartSpanAuto(ctx)
 	defer otelConfig.Tracing.EndSpan(span)
 
 	otelConfig.Tracing.AddAttributes(span, nil, attribute.String("testFunc", "2"))
@@ -96,7 +96,7 @@ func testFunc(ctx context.Context) {
 }
 
 func testFunc2(ctx context.Context) {
-	ctx, span := otelConfig.Tracing.GetTracer().Start(ctx, "Test Func 2")
+	ctx, span := otelConfig.Tracing.StartSpanAuto(ctx)
 	otelConfig.Tracing.RecordError(ctx, span, errs.CreateError(errs.BAD_REQUEST, errs.BadRequest, "Something Went Wrong"))
 	defer otelConfig.Tracing.EndSpan(span)
 }
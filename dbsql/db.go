@@ -0,0 +1,250 @@
+package apw_sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+
+	"otel-library/otelBuilder"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DB instruments an existing *sql.DB whose driver cannot be wrapped, for example one created by
+// another library. Queries, execs, prepares and transactions started through DB emit client
+// spans; the embedded *sql.DB remains available for everything else.
+type DB struct {
+	*sql.DB
+	inst *instrumentation
+}
+
+// WrapDB returns a DB that instruments calls made through it on db.
+func WrapDB(db *sql.DB, o *otelBuilder.Otel, opts ...Option) *DB {
+	return &DB{DB: db, inst: newInstrumentation(o, opts)}
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	ctx, span := db.inst.start(ctx, "query", query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	return db.inst.wrapRows(ctx, span, rows, err)
+}
+
+func (db *DB) Query(query string, args ...any) (*Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := db.inst.start(ctx, "query", query)
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	db.inst.end(ctx, span, row.Err())
+	return row
+}
+
+func (db *DB) QueryRow(query string, args ...any) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := db.inst.start(ctx, "exec", query)
+	result, err := db.DB.ExecContext(ctx, query, args...)
+	db.inst.endWithResult(ctx, span, result, err)
+	return result, err
+}
+
+func (db *DB) Exec(query string, args ...any) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+func (db *DB) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	ctx, span := db.inst.start(ctx, "prepare", query)
+	stmt, err := db.DB.PrepareContext(ctx, query)
+	db.inst.end(ctx, span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{Stmt: stmt, query: query, inst: db.inst}, nil
+}
+
+func (db *DB) Prepare(query string) (*Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	spanCtx, span := db.inst.start(ctx, "begin", "")
+	tx, err := db.DB.BeginTx(spanCtx, opts)
+	db.inst.end(spanCtx, span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, ctx: ctx, inst: db.inst}, nil
+}
+
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// Tx is an instrumented *sql.Tx returned by DB.BeginTx.
+type Tx struct {
+	*sql.Tx
+	ctx  context.Context
+	inst *instrumentation
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...any) (*Rows, error) {
+	ctx, span := tx.inst.start(ctx, "query", query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	return tx.inst.wrapRows(ctx, span, rows, err)
+}
+
+func (tx *Tx) Query(query string, args ...any) (*Rows, error) {
+	return tx.QueryContext(tx.ctx, query, args...)
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := tx.inst.start(ctx, "query", query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	tx.inst.end(ctx, span, row.Err())
+	return row
+}
+
+func (tx *Tx) QueryRow(query string, args ...any) *sql.Row {
+	return tx.QueryRowContext(tx.ctx, query, args...)
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := tx.inst.start(ctx, "exec", query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	tx.inst.endWithResult(ctx, span, result, err)
+	return result, err
+}
+
+func (tx *Tx) Exec(query string, args ...any) (sql.Result, error) {
+	return tx.ExecContext(tx.ctx, query, args...)
+}
+
+func (tx *Tx) PrepareContext(ctx context.Context, query string) (*Stmt, error) {
+	ctx, span := tx.inst.start(ctx, "prepare", query)
+	stmt, err := tx.Tx.PrepareContext(ctx, query)
+	tx.inst.end(ctx, span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{Stmt: stmt, query: query, inst: tx.inst}, nil
+}
+
+func (tx *Tx) Prepare(query string) (*Stmt, error) {
+	return tx.PrepareContext(tx.ctx, query)
+}
+
+// StmtContext returns a transaction-specific statement from stmt, prepared on the DB, whose
+// queries and execs are instrumented like stmt's.
+func (tx *Tx) StmtContext(ctx context.Context, stmt *Stmt) *Stmt {
+	return &Stmt{Stmt: tx.Tx.StmtContext(ctx, stmt.Stmt), query: stmt.query, inst: tx.inst}
+}
+
+func (tx *Tx) Stmt(stmt *Stmt) *Stmt {
+	return tx.StmtContext(tx.ctx, stmt)
+}
+
+func (tx *Tx) Commit() error {
+	ctx, span := tx.inst.start(tx.ctx, "commit", "")
+	err := tx.Tx.Commit()
+	tx.inst.end(ctx, span, err)
+	return err
+}
+
+func (tx *Tx) Rollback() error {
+	ctx, span := tx.inst.start(tx.ctx, "rollback", "")
+	err := tx.Tx.Rollback()
+	tx.inst.end(ctx, span, err)
+	return err
+}
+
+// Stmt is an instrumented *sql.Stmt returned by DB.PrepareContext.
+type Stmt struct {
+	*sql.Stmt
+	query string
+	inst  *instrumentation
+}
+
+func (s *Stmt) QueryContext(ctx context.Context, args ...any) (*Rows, error) {
+	ctx, span := s.inst.start(ctx, "query", s.query)
+	rows, err := s.Stmt.QueryContext(ctx, args...)
+	return s.inst.wrapRows(ctx, span, rows, err)
+}
+
+func (s *Stmt) Query(args ...any) (*Rows, error) {
+	return s.QueryContext(context.Background(), args...)
+}
+
+func (s *Stmt) QueryRowContext(ctx context.Context, args ...any) *sql.Row {
+	ctx, span := s.inst.start(ctx, "query", s.query)
+	row := s.Stmt.QueryRowContext(ctx, args...)
+	s.inst.end(ctx, span, row.Err())
+	return row
+}
+
+func (s *Stmt) QueryRow(args ...any) *sql.Row {
+	return s.QueryRowContext(context.Background(), args...)
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, args ...any) (sql.Result, error) {
+	ctx, span := s.inst.start(ctx, "exec", s.query)
+	result, err := s.Stmt.ExecContext(ctx, args...)
+	s.inst.endWithResult(ctx, span, result, err)
+	return result, err
+}
+
+func (s *Stmt) Exec(args ...any) (sql.Result, error) {
+	return s.ExecContext(context.Background(), args...)
+}
+
+// Rows is an instrumented *sql.Rows returned by the query methods of DB, Tx and Stmt. Its span
+// covers reading the results: it ends, with db.rows_returned set, once the rows are closed,
+// either by Close or by Next reaching the end of the last result set.
+type Rows struct {
+	*sql.Rows
+	ctx   context.Context
+	span  trace.Span
+	inst  *instrumentation
+	count int64
+	once  sync.Once
+}
+
+// wrapRows ends span right away when the query failed, and otherwise hands it to the returned
+// Rows.
+func (i *instrumentation) wrapRows(ctx context.Context, span trace.Span, rows *sql.Rows, err error) (*Rows, error) {
+	if err != nil {
+		i.end(ctx, span, err)
+		return nil, err
+	}
+	return &Rows{Rows: rows, ctx: ctx, span: span, inst: i}, nil
+}
+
+func (r *Rows) Next() bool {
+	if r.Rows.Next() {
+		r.count++
+		return true
+	}
+	// Columns only fails once the rows are closed, which tells the end of the last result set
+	// apart from the end of one followed by NextResultSet.
+	if _, err := r.Rows.Columns(); err != nil {
+		r.end(nil)
+	}
+	return false
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.end(err)
+	return err
+}
+
+// end ends the span once, recording the iteration error and closeErr.
+func (r *Rows) end(closeErr error) {
+	r.once.Do(func() {
+		r.span.SetAttributes(rowsReturnedKey.Int64(r.count))
+		r.inst.end(r.ctx, r.span, errors.Join(r.Rows.Err(), closeErr))
+	})
+}
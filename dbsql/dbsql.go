@@ -0,0 +1,163 @@
+// Package apw_sql instruments database/sql. Open and WrapDriver wrap a driver so that every
+// query, exec, prepare and transaction emits a client span; WrapDB instruments an existing
+// *sql.DB; RecordPoolMetrics reports connection-pool statistics.
+package apw_sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+
+	"otel-library/otelBuilder"
+	apw_tracing "otel-library/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scopeName is the instrumentation scope of the spans and metrics recorded by this package.
+const scopeName = "otel-library/dbsql"
+
+const (
+	rowsAffectedKey = attribute.Key("db.rows_affected")
+	rowsReturnedKey = attribute.Key("db.rows_returned")
+	// dbStatementKey is the pre-1.25 semantic convention key of db.query.text, still read by
+	// most backends; the sanitized statement is recorded under both.
+	dbStatementKey = attribute.Key("db.statement")
+)
+
+type config struct {
+	system            string
+	dbName            string
+	sanitize          func(string) string
+	quotedIdentifiers bool
+	omitStatement     bool
+}
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// WithDBSystem sets db.system, for example "postgresql" or "sqlite". Open defaults it to the
+// driver name.
+func WithDBSystem(system string) Option {
+	return func(c *config) {
+		c.system = system
+	}
+}
+
+// WithDBName sets db.namespace on every span and pool metric.
+func WithDBName(name string) Option {
+	return func(c *config) {
+		c.dbName = name
+	}
+}
+
+// WithStatementSanitizer replaces SanitizeStatement as the function applied to statements
+// before they are recorded in db.statement and db.query.text.
+func WithStatementSanitizer(sanitize func(string) string) Option {
+	return func(c *config) {
+		c.sanitize = sanitize
+	}
+}
+
+// WithQuotedIdentifiers makes the default sanitizer keep double-quoted text, for dialects such
+// as PostgreSQL, SQLite or Oracle where double quotes delimit identifiers rather than strings.
+func WithQuotedIdentifiers() Option {
+	return func(c *config) {
+		c.quotedIdentifiers = true
+	}
+}
+
+// WithoutStatement stops db.statement and db.query.text from being recorded.
+func WithoutStatement() Option {
+	return func(c *config) {
+		c.omitStatement = true
+	}
+}
+
+// instrumentation creates the spans shared by the driver and *sql.DB wrappers.
+type instrumentation struct {
+	tracing apw_tracing.OtelTracing
+	cfg     config
+}
+
+func newInstrumentation(o *otelBuilder.Otel, opts []Option) *instrumentation {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.sanitize == nil {
+		quotedIdentifiers := cfg.quotedIdentifiers
+		cfg.sanitize = func(query string) string {
+			return sanitize(query, quotedIdentifiers)
+		}
+	}
+	return &instrumentation{tracing: o.Tracer(scopeName, ""), cfg: cfg}
+}
+
+// commonAttributes returns the db.system and db.namespace attributes.
+func (i *instrumentation) commonAttributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if i.cfg.system != "" {
+		attrs = append(attrs, semconv.DBSystemKey.String(i.cfg.system))
+	}
+	if i.cfg.dbName != "" {
+		attrs = append(attrs, semconv.DBNamespace(i.cfg.dbName))
+	}
+	return attrs
+}
+
+// start starts a client span named "sql.<operation>" for the given statement, which may be empty.
+func (i *instrumentation) start(ctx context.Context, operation, query string) (context.Context, trace.Span) {
+	attrs := i.commonAttributes()
+	if query != "" && !i.cfg.omitStatement {
+		statement := i.cfg.sanitize(query)
+		attrs = append(attrs, dbStatementKey.String(statement), semconv.DBQueryText(statement))
+	}
+	return i.tracing.StartSpan(ctx, "sql."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end records err, unless it is driver.ErrSkip which only asks database/sql to retry another
+// way, and ends the span.
+func (i *instrumentation) end(ctx context.Context, span trace.Span, err error) {
+	if err != nil && !errors.Is(err, driver.ErrSkip) {
+		i.tracing.RecordError(ctx, span, err)
+	}
+	i.tracing.EndSpan(span)
+}
+
+// endWithResult records the rows affected by an exec before ending the span.
+func (i *instrumentation) endWithResult(ctx context.Context, span trace.Span, result driver.Result, err error) {
+	if err == nil && result != nil {
+		if rows, rowsErr := result.RowsAffected(); rowsErr == nil {
+			span.SetAttributes(rowsAffectedKey.Int64(rows))
+		}
+	}
+	i.end(ctx, span, err)
+}
+
+// Open opens a database like sql.Open, with the registered driver driverName wrapped by
+// WrapDriver. db.system defaults to driverName.
+func Open(o *otelBuilder.Otel, driverName, dsn string, opts ...Option) (*sql.DB, error) {
+	// sql.Open does not connect, it only looks the driver up.
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+
+	opts = append([]Option{WithDBSystem(driverName)}, opts...)
+	connector, err := WrapDriver(d, o, opts...).(driver.DriverContext).OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(connector), nil
+}
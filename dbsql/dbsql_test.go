@@ -0,0 +1,303 @@
+package apw_sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	apw_logging "otel-library/logs"
+	"otel-library/otelBuilder"
+	apw_tracing "otel-library/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// fakeDriver is a minimal driver whose queries return rows and whose execs report rowsAffected.
+// Statements convert their arguments with upperConverter, and the last arguments passed to a
+// statement's Exec are kept in execArgs.
+type fakeDriver struct {
+	rows     [][]driver.Value
+	execArgs []driver.Value
+	queryErr error
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return &fakeStmt{d: c.d}, nil }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c *fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	if c.d.queryErr != nil {
+		return nil, c.d.queryErr
+	}
+	return &fakeRows{rows: c.d.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(3), nil
+}
+
+type fakeStmt struct {
+	d *fakeDriver
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.execArgs = args
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: s.d.rows}, nil
+}
+
+func (s *fakeStmt) ColumnConverter(int) driver.ValueConverter {
+	return upperConverter{}
+}
+
+// upperConverter marks every argument so tests can tell it ran instead of the default one.
+type upperConverter struct{}
+
+func (upperConverter) ConvertValue(v any) (driver.Value, error) {
+	return "converted", nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	rows [][]driver.Value
+	next int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// newTestOtel returns an Otel whose spans are recorded by the returned recorder.
+func newTestOtel() (*otelBuilder.Otel, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	l := apw_logging.NewNoopLogging()
+	tracing := apw_tracing.NewTracing(tp.Tracer("test"), l, apw_tracing.WithTracerProvider(tp))
+	return otelBuilder.NewOtel(tracing, nil, l), recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestWrapDriverQuerySpan(t *testing.T) {
+	o, recorder := newTestOtel()
+	d := &fakeDriver{rows: [][]driver.Value{{int64(1)}, {int64(2)}}}
+	connector, err := WrapDriver(d, o, WithDBSystem("fake"), WithDBName("shop")).(driver.DriverContext).OpenConnector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT id FROM users WHERE name = 'alice' AND age > 30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "sql.query" {
+		t.Errorf("span name = %q, want sql.query", span.Name())
+	}
+	want := map[attribute.Key]attribute.Value{
+		semconv.DBSystemKey:    attribute.StringValue("fake"),
+		semconv.DBNamespaceKey: attribute.StringValue("shop"),
+		dbStatementKey:         attribute.StringValue("SELECT id FROM users WHERE name = ? AND age > ?"),
+		semconv.DBQueryTextKey: attribute.StringValue("SELECT id FROM users WHERE name = ? AND age > ?"),
+		rowsReturnedKey:        attribute.Int64Value(2),
+	}
+	for key, value := range want {
+		if got, ok := spanAttribute(span, key); !ok || got != value {
+			t.Errorf("%s = %v, want %v", key, got.Emit(), value.Emit())
+		}
+	}
+}
+
+func TestWrapDriverRecordsQueryError(t *testing.T) {
+	o, recorder := newTestOtel()
+	d := &fakeDriver{queryErr: errors.New("syntax error")}
+	connector, _ := WrapDriver(d, o).(driver.DriverContext).OpenConnector("")
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	if _, err := db.QueryContext(context.Background(), "SELECT"); err == nil {
+		t.Fatal("QueryContext succeeded, want an error")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Fatalf("got spans %v, want one span with an error status", spans)
+	}
+}
+
+func TestWrapDriverKeepsColumnConverter(t *testing.T) {
+	o, _ := newTestOtel()
+	d := &fakeDriver{}
+	connector, _ := WrapDriver(d, o).(driver.DriverContext).OpenConnector("")
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	stmt, err := db.Prepare("UPDATE users SET age = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec(int64(31)); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.execArgs) != 1 || d.execArgs[0] != "converted" {
+		t.Errorf("driver received %v, want the statement's column converter to run", d.execArgs)
+	}
+}
+
+func TestWrapDBRowsSpanCoversIteration(t *testing.T) {
+	o, recorder := newTestOtel()
+	d := &fakeDriver{rows: [][]driver.Value{{int64(1)}, {int64(2)}, {int64(3)}}}
+	db := WrapDB(sql.OpenDB(dsnConnector{driver: d}), o)
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT id FROM users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Ended()) != 0 {
+		t.Fatal("span ended before the rows were read")
+	}
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The span ends when Next reaches the end, even without Close.
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got, _ := spanAttribute(spans[0], rowsReturnedKey); got != attribute.Int64Value(3) {
+		t.Errorf("%s = %v, want 3", rowsReturnedKey, got.Emit())
+	}
+
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Ended()) != 1 {
+		t.Error("Close ended the span a second time")
+	}
+}
+
+func TestWrapDBExecRecordsRowsAffected(t *testing.T) {
+	o, recorder := newTestOtel()
+	db := WrapDB(sql.OpenDB(dsnConnector{driver: &fakeDriver{}}), o, WithQuotedIdentifiers())
+	defer db.Close()
+
+	if _, err := db.Exec(`DELETE FROM "order" WHERE id = 7`); err != nil {
+		t.Fatal(err)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	if got, _ := spanAttribute(spans[0], rowsAffectedKey); got != attribute.Int64Value(3) {
+		t.Errorf("%s = %v, want 3", rowsAffectedKey, got.Emit())
+	}
+	for _, key := range []attribute.Key{dbStatementKey, semconv.DBQueryTextKey} {
+		if got, _ := spanAttribute(spans[0], key); got.AsString() != `DELETE FROM "order" WHERE id = ?` {
+			t.Errorf("%s = %q", key, got.AsString())
+		}
+	}
+}
+
+func TestWrapDBTxPrepareSpans(t *testing.T) {
+	o, recorder := newTestOtel()
+	db := WrapDB(sql.OpenDB(dsnConnector{driver: &fakeDriver{}}), o)
+	defer db.Close()
+
+	prepared, err := db.Prepare("UPDATE users SET age = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer prepared.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt, err := tx.Prepare("DELETE FROM users WHERE id = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stmt.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Stmt(prepared).Exec(int64(31)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, span := range recorder.Ended() {
+		statement, _ := spanAttribute(span, dbStatementKey)
+		got = append(got, span.Name()+" "+statement.AsString())
+	}
+	want := []string{
+		"sql.prepare UPDATE users SET age = ?",
+		"sql.begin ",
+		"sql.prepare DELETE FROM users WHERE id = ?",
+		"sql.exec DELETE FROM users WHERE id = ?",
+		"sql.exec UPDATE users SET age = ?",
+		"sql.commit ",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("spans:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,372 @@
+package apw_sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+
+	"otel-library/otelBuilder"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WrapDriver returns a driver that instruments every connection opened through d. Register it
+// with sql.Register, or use Open.
+func WrapDriver(d driver.Driver, o *otelBuilder.Otel, opts ...Option) driver.Driver {
+	return &otelDriver{Driver: d, inst: newInstrumentation(o, opts)}
+}
+
+// WrapConnector returns a connector that instruments every connection created by c, for use
+// with sql.OpenDB.
+func WrapConnector(c driver.Connector, o *otelBuilder.Otel, opts ...Option) driver.Connector {
+	d := &otelDriver{Driver: c.Driver(), inst: newInstrumentation(o, opts)}
+	return &otelConnector{Connector: c, driver: d}
+}
+
+type otelDriver struct {
+	driver.Driver
+	inst *instrumentation
+}
+
+func (d *otelDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &otelConn{Conn: conn, inst: d.inst}, nil
+}
+
+func (d *otelDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.Driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &otelConnector{Connector: connector, driver: d}, nil
+	}
+	return &otelConnector{Connector: dsnConnector{dsn: name, driver: d.Driver}, driver: d}, nil
+}
+
+// dsnConnector is the connector used for drivers that do not implement driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type otelConnector struct {
+	driver.Connector
+	driver *otelDriver
+}
+
+func (c *otelConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &otelConn{Conn: conn, inst: c.driver.inst}, nil
+}
+
+func (c *otelConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type otelConn struct {
+	driver.Conn
+	inst *instrumentation
+}
+
+func (c *otelConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *otelConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	ctx, span := c.inst.start(ctx, "prepare", query)
+
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	c.inst.end(ctx, span, err)
+	if err != nil {
+		return nil, err
+	}
+	return wrapStmt(stmt, c.Conn, query, c.inst), nil
+}
+
+func (c *otelConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *otelConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	spanCtx, span := c.inst.start(ctx, "begin", "")
+
+	var tx driver.Tx
+	var err error
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = beginner.BeginTx(spanCtx, opts)
+	} else {
+		tx, err = c.Conn.Begin()
+	}
+	c.inst.end(spanCtx, span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &otelTx{Tx: tx, ctx: ctx, inst: c.inst}, nil
+}
+
+func (c *otelConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.inst.start(ctx, "query", query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		c.inst.end(ctx, span, err)
+		return nil, err
+	}
+	return &otelRows{Rows: rows, ctx: ctx, span: span, inst: c.inst}, nil
+}
+
+func (c *otelConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	ctx, span := c.inst.start(ctx, "exec", query)
+	result, err := execer.ExecContext(ctx, query, args)
+	c.inst.endWithResult(ctx, span, result, err)
+	return result, err
+}
+
+func (c *otelConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *otelConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *otelConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *otelConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// wrapStmt instruments stmt. The wrapper only implements driver.ColumnConverter when stmt does,
+// because database/sql prefers a statement's column converter over the default conversion.
+func wrapStmt(stmt driver.Stmt, conn driver.Conn, query string, inst *instrumentation) driver.Stmt {
+	s := &otelStmt{Stmt: stmt, conn: conn, query: query, inst: inst}
+	if converter, ok := stmt.(driver.ColumnConverter); ok {
+		return &otelColumnConverterStmt{otelStmt: s, converter: converter}
+	}
+	return s
+}
+
+type otelStmt struct {
+	driver.Stmt
+	conn  driver.Conn
+	query string
+	inst  *instrumentation
+}
+
+// otelColumnConverterStmt is an otelStmt whose driver statement converts its own arguments.
+type otelColumnConverterStmt struct {
+	*otelStmt
+	converter driver.ColumnConverter
+}
+
+func (s *otelColumnConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.converter.ColumnConverter(idx)
+}
+
+func (s *otelStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := s.inst.start(ctx, "exec", s.query)
+
+	var result driver.Result
+	var err error
+	if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = s.Stmt.Exec(values)
+		}
+	}
+	s.inst.endWithResult(ctx, span, result, err)
+	return result, err
+}
+
+func (s *otelStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := s.inst.start(ctx, "query", s.query)
+
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.Stmt.Query(values)
+		}
+	}
+	if err != nil {
+		s.inst.end(ctx, span, err)
+		return nil, err
+	}
+	return &otelRows{Rows: rows, ctx: ctx, span: span, inst: s.inst}, nil
+}
+
+// CheckNamedValue defers to the statement's checker, then to the connection's one, as
+// database/sql would for an unwrapped statement.
+func (s *otelStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	if checker, ok := s.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// namedValuesToValues converts arguments for drivers that only support positional values.
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("sql: driver does not support the use of Named Parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+type otelTx struct {
+	driver.Tx
+	ctx  context.Context
+	inst *instrumentation
+}
+
+func (t *otelTx) Commit() error {
+	ctx, span := t.inst.start(t.ctx, "commit", "")
+	err := t.Tx.Commit()
+	t.inst.end(ctx, span, err)
+	return err
+}
+
+func (t *otelTx) Rollback() error {
+	ctx, span := t.inst.start(t.ctx, "rollback", "")
+	err := t.Tx.Rollback()
+	t.inst.end(ctx, span, err)
+	return err
+}
+
+// otelRows keeps the query span open until the rows are closed so that it covers reading the
+// results and can report how many rows were returned.
+type otelRows struct {
+	driver.Rows
+	ctx   context.Context
+	span  trace.Span
+	inst  *instrumentation
+	count int64
+	err   error
+}
+
+func (r *otelRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case !errors.Is(err, io.EOF):
+		r.err = err
+	}
+	return err
+}
+
+func (r *otelRows) Close() error {
+	err := r.Rows.Close()
+	r.span.SetAttributes(rowsReturnedKey.Int64(r.count))
+	if r.err != nil {
+		err = errors.Join(r.err, err)
+	}
+	r.inst.end(r.ctx, r.span, err)
+	return err
+}
+
+func (r *otelRows) HasNextResultSet() bool {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *otelRows) NextResultSet() error {
+	if rs, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return rs.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *otelRows) ColumnTypeScanType(index int) reflect.Type {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return ct.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(any)).Elem()
+}
+
+func (r *otelRows) ColumnTypeDatabaseTypeName(index int) string {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return ct.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *otelRows) ColumnTypeLength(index int) (int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return ct.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *otelRows) ColumnTypeNullable(index int) (bool, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return ct.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *otelRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if ct, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return ct.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
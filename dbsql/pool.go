@@ -0,0 +1,70 @@
+package apw_sql
+
+import (
+	"context"
+	"database/sql"
+
+	apw_metrics "otel-library/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// RecordPoolMetrics reports the connection-pool statistics of db on every metric collection:
+// db.client.connections.usage (split by db.client.connections.state=used|idle), db.client.connections.max,
+// db.client.connections.wait_count and db.client.connections.wait_duration in milliseconds.
+// Unregister the returned registration when db is closed.
+func RecordPoolMetrics(db *sql.DB, m apw_metrics.OtelMetric, opts ...Option) (metric.Registration, error) {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	m = m.Meter(scopeName, "")
+
+	usage, err := m.CreateObservableGauge("db.client.connections.usage",
+		metric.WithDescription("Number of connections that are currently in the state described by the state attribute"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, err
+	}
+	max, err := m.CreateObservableGauge("db.client.connections.max",
+		metric.WithDescription("Maximum number of open connections allowed"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, err
+	}
+	waitCount, err := m.CreateObservableCounter("db.client.connections.wait_count",
+		metric.WithDescription("Total number of connections waited for"),
+		metric.WithUnit("{connection}"))
+	if err != nil {
+		return nil, err
+	}
+	waitDuration, err := m.CreateObservableCounter("db.client.connections.wait_duration",
+		metric.WithDescription("Total time blocked waiting for a new connection"),
+		metric.WithUnit("ms"))
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs []attribute.KeyValue
+	if cfg.system != "" {
+		attrs = append(attrs, semconv.DBSystemKey.String(cfg.system))
+	}
+	if cfg.dbName != "" {
+		attrs = append(attrs, semconv.DBNamespace(cfg.dbName))
+	}
+	common := metric.WithAttributes(attrs...)
+	used := metric.WithAttributes(append(attrs[:len(attrs):len(attrs)], semconv.DBClientConnectionsStateUsed)...)
+	idle := metric.WithAttributes(append(attrs[:len(attrs):len(attrs)], semconv.DBClientConnectionsStateIdle)...)
+
+	return m.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := db.Stats()
+		o.ObserveInt64(usage, int64(stats.InUse), used)
+		o.ObserveInt64(usage, int64(stats.Idle), idle)
+		o.ObserveInt64(max, int64(stats.MaxOpenConnections), common)
+		o.ObserveInt64(waitCount, stats.WaitCount, common)
+		o.ObserveInt64(waitDuration, stats.WaitDuration.Milliseconds(), common)
+		return nil
+	}, usage, max, waitCount, waitDuration)
+}
@@ -0,0 +1,111 @@
+package apw_sql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	apw_metrics "otel-library/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// poolValues returns the int64 data points of every metric, keyed by metric name and the
+// db.client.connections.state attribute, if any.
+func poolValues(t *testing.T, reader *sdkmetric.ManualReader) map[string]int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]int64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			var points []metricdata.DataPoint[int64]
+			switch data := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				points = data.DataPoints
+			case metricdata.Sum[int64]:
+				points = data.DataPoints
+			}
+			for _, point := range points {
+				key := m.Name
+				if state, ok := point.Attributes.Value(semconv.DBClientConnectionsStateKey); ok {
+					key += "/" + state.AsString()
+				}
+				if system, _ := point.Attributes.Value(semconv.DBSystemKey); system != attribute.StringValue("fake") {
+					t.Errorf("%s has %s = %q, want fake", key, semconv.DBSystemKey, system.Emit())
+				}
+				values[key] = point.Value
+			}
+		}
+	}
+	return values
+}
+
+func TestRecordPoolMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	m := apw_metrics.NewMetric(mp.Meter("test"), apw_metrics.WithMeterProvider(mp))
+
+	db := sql.OpenDB(dsnConnector{driver: &fakeDriver{}})
+	defer db.Close()
+	db.SetMaxOpenConns(2)
+	registration, err := RecordPoolMetrics(db, m, WithDBSystem("fake"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registration.Unregister()
+
+	ctx := context.Background()
+	held, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	released.Close()
+
+	// Exhaust the pool so the next caller waits until held is released.
+	busy, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waited := make(chan *sql.Conn)
+	go func() {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Error(err)
+		}
+		waited <- conn
+	}()
+	for db.Stats().WaitCount == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	held.Close()
+	defer (<-waited).Close()
+	busy.Close()
+
+	values := poolValues(t, reader)
+	want := map[string]int64{
+		"db.client.connections.usage/used": 1,
+		"db.client.connections.usage/idle": 1,
+		"db.client.connections.max":        2,
+		"db.client.connections.wait_count": 1,
+	}
+	for key, value := range want {
+		if got, ok := values[key]; !ok || got != value {
+			t.Errorf("%s = %d, want %d", key, got, value)
+		}
+	}
+	if got := values["db.client.connections.wait_duration"]; got < 10 {
+		t.Errorf("db.client.connections.wait_duration = %dms, want at least 10ms", got)
+	}
+}
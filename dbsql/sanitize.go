@@ -0,0 +1,213 @@
+package apw_sql
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SanitizeStatement replaces string, numeric and hex literals in a SQL statement with "?",
+// drops -- and /* */ comments and collapses whitespace, so that db.statement never carries
+// user data and statements that only differ by their values look the same. Double-quoted text
+// is replaced like a string literal, as MySQL reads it; use WithQuotedIdentifiers for dialects
+// such as PostgreSQL where double quotes delimit identifiers.
+func SanitizeStatement(query string) string {
+	return sanitize(query, false)
+}
+
+// sanitize implements SanitizeStatement. With quotedIdentifiers, double-quoted text is kept as
+// an identifier instead of being replaced. Backquoted identifiers are always kept.
+func sanitize(query string, quotedIdentifiers bool) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	runes := []rune(query)
+	space := false
+	// separate writes the single space that stands for any whitespace or comment skipped
+	// since the previous token.
+	separate := func() {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+		case r == '-' && at(runes, i+1) == '-':
+			i = skipPast(runes, i+2, "\n")
+			space = true
+		case r == '/' && at(runes, i+1) == '*':
+			i = skipPast(runes, i+2, "*/")
+			space = true
+		case r == '\'', r == '"' && !quotedIdentifiers:
+			i = skipQuoted(runes, i, r, true)
+			separate()
+			b.WriteByte('?')
+		case r == '"', r == '`':
+			end := skipQuoted(runes, i, r, false)
+			separate()
+			b.WriteString(string(runes[i : end+1]))
+			i = end
+		case isLiteralPrefix(runes, i):
+			i = skipQuoted(runes, i+1, '\'', true)
+			separate()
+			b.WriteByte('?')
+		case r == '$' && dollarTag(runes, i) != "":
+			tag := dollarTag(runes, i)
+			i = skipPast(runes, i+len([]rune(tag)), tag)
+			separate()
+			b.WriteByte('?')
+		case startsNumber(runes, i):
+			i = skipNumber(runes, i)
+			separate()
+			b.WriteByte('?')
+		default:
+			separate()
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// at returns the rune at i, or 0 past the end of runes.
+func at(runes []rune, i int) rune {
+	if i < len(runes) {
+		return runes[i]
+	}
+	return 0
+}
+
+// skipPast returns the index of the last rune of the first occurrence of end at or after
+// start, or the last index of runes when end does not occur.
+func skipPast(runes []rune, start int, end string) int {
+	terminator := []rune(end)
+	for i := start; i+len(terminator) <= len(runes); i++ {
+		if string(runes[i:i+len(terminator)]) == end {
+			return i + len(terminator) - 1
+		}
+	}
+	return len(runes) - 1
+}
+
+// skipQuoted returns the index of the quote closing the quoted text that starts at i. A doubled
+// quote is an escaped quote and, with backslashEscapes, so is a backslash followed by any rune.
+// Unterminated text runs to the end of the statement.
+func skipQuoted(runes []rune, i int, quote rune, backslashEscapes bool) int {
+	for i++; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if at(runes, i+1) != quote {
+				return i
+			}
+			i++
+		}
+	}
+	return len(runes) - 1
+}
+
+// isLiteralPrefix reports whether i starts a prefixed string literal such as X'1F', B'01',
+// N'text' or E'text'.
+func isLiteralPrefix(runes []rune, i int) bool {
+	if at(runes, i+1) != '\'' || partOfIdentifier(runes, i) {
+		return false
+	}
+	switch runes[i] {
+	case 'x', 'X', 'b', 'B', 'n', 'N', 'e', 'E':
+		return true
+	}
+	return false
+}
+
+// dollarTag returns the opening tag of a PostgreSQL dollar-quoted string starting at i, such as
+// "$$" or "$body$", or "" when i does not start one. Placeholders such as "$1" are not tags.
+func dollarTag(runes []rune, i int) string {
+	if partOfIdentifier(runes, i) {
+		return ""
+	}
+	j := i + 1
+	if j < len(runes) && unicode.IsDigit(runes[j]) {
+		return ""
+	}
+	for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+		j++
+	}
+	if j < len(runes) && runes[j] == '$' {
+		return string(runes[i : j+1])
+	}
+	return ""
+}
+
+// startsNumber reports whether i starts a numeric literal rather than continuing an identifier
+// or placeholder.
+func startsNumber(runes []rune, i int) bool {
+	r := runes[i]
+	if r == '.' {
+		r = at(runes, i+1)
+	}
+	return unicode.IsDigit(r) && !partOfIdentifier(runes, i)
+}
+
+// skipNumber returns the index of the last rune of the numeric literal starting at i: a
+// decimal with an optional fraction and exponent, or a 0x hexadecimal or 0b binary number.
+func skipNumber(runes []rune, i int) int {
+	if runes[i] == '0' {
+		switch at(runes, i+1) {
+		case 'x', 'X':
+			if isHexDigit(at(runes, i+2)) {
+				return skipWhile(runes, i+2, isHexDigit)
+			}
+		case 'b', 'B':
+			if isBinaryDigit(at(runes, i+2)) {
+				return skipWhile(runes, i+2, isBinaryDigit)
+			}
+		}
+	}
+
+	i = skipWhile(runes, i, unicode.IsDigit)
+	if at(runes, i+1) == '.' {
+		i = skipWhile(runes, i+1, unicode.IsDigit)
+	}
+	if e := at(runes, i+1); e == 'e' || e == 'E' {
+		j := i + 2
+		if sign := at(runes, j); sign == '+' || sign == '-' {
+			j++
+		}
+		if unicode.IsDigit(at(runes, j)) {
+			i = skipWhile(runes, j, unicode.IsDigit)
+		}
+	}
+	return i
+}
+
+// skipWhile returns the index of the last rune from i on, i included, for which ok holds.
+func skipWhile(runes []rune, i int, ok func(rune) bool) int {
+	for i+1 < len(runes) && ok(runes[i+1]) {
+		i++
+	}
+	return i
+}
+
+func isHexDigit(r rune) bool {
+	return unicode.IsDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func isBinaryDigit(r rune) bool {
+	return r == '0' || r == '1'
+}
+
+// partOfIdentifier reports whether the rune at i continues an identifier or placeholder such
+// as "table1" or "$1".
+func partOfIdentifier(runes []rune, i int) bool {
+	if i == 0 {
+		return false
+	}
+	prev := runes[i-1]
+	return unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '_' || prev == '$' || prev == '@' || prev == ':'
+}
@@ -0,0 +1,42 @@
+package apw_sql
+
+import "testing"
+
+func TestSanitizeStatement(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"string literal", "SELECT * FROM users WHERE name = 'alice'", "SELECT * FROM users WHERE name = ?"},
+		{"escaped quote", "SELECT 'it''s'", "SELECT ?"},
+		{"backslash escape", `SELECT 'it\'s secret'`, "SELECT ?"},
+		{"double-quoted literal", `SELECT * FROM users WHERE name = "alice"`, "SELECT * FROM users WHERE name = ?"},
+		{"backquoted identifier", "SELECT `col1` FROM `t2`", "SELECT `col1` FROM `t2`"},
+		{"integer and decimal", "WHERE a = 42 AND b = 3.14 AND c = .5", "WHERE a = ? AND b = ? AND c = ?"},
+		{"exponent", "WHERE a = 1e10 OR a = 2.5E-3", "WHERE a = ? OR a = ?"},
+		{"hex and binary", "WHERE a = 0x1F OR b = 0b101", "WHERE a = ? OR b = ?"},
+		{"prefixed literals", "WHERE a = X'1F' OR b = N'name' OR c = E'x\\'y'", "WHERE a = ? OR b = ? OR c = ?"},
+		{"identifiers and placeholders", "SELECT col1 FROM t2 WHERE id = $1 AND x = :x2 AND y = @p3", "SELECT col1 FROM t2 WHERE id = $1 AND x = :x2 AND y = @p3"},
+		{"line comment", "SELECT 1 -- user's secret\nFROM t", "SELECT ? FROM t"},
+		{"block comment", "SELECT /* don't leak 'this' */ name FROM t", "SELECT name FROM t"},
+		{"dollar quoted", "SELECT $$secret$$, $tag$more$tag$", "SELECT ?, ?"},
+		{"whitespace", "  SELECT\n\t*   FROM t  ", "SELECT * FROM t"},
+		{"unterminated string", "SELECT 'secret", "SELECT ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeStatement(tt.query); got != tt.want {
+				t.Errorf("SanitizeStatement(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeQuotedIdentifiers(t *testing.T) {
+	query := `SELECT "user id" FROM "order" WHERE name = 'alice'`
+	want := `SELECT "user id" FROM "order" WHERE name = ?`
+	if got := sanitize(query, true); got != want {
+		t.Errorf("sanitize(%q, true) = %q, want %q", query, got, want)
+	}
+}
@@ -1,11 +1,11 @@
 package errs
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"runtime"
 	"strings"
-
-	"github.com/pkg/errors"
 )
 
 type Status string
@@ -41,6 +41,8 @@ type ErrorService struct {
 	ErrorCode    string
 	ErrorMessage string
 	StackTrace   string // Store stack trace as a string
+	// Stack is the structured stack captured at creation, nil when stacks are disabled.
+	Stack *Stack
 }
 
 // Error implements the error interface for ErrorService
@@ -48,14 +50,33 @@ func (e *ErrorService) Error() string {
 	return fmt.Sprintf(" Error: %s", e.ErrorMessage)
 }
 
-// GetStackTrace returns the stored stack trace
+// GetStackTrace returns the stack trace rendered in the configured StackFormat
 func (e *ErrorService) GetStackTrace() string {
-	return formatStackTrace(e.StackTrace) // Format errors.StackTrace for pretty printing
+	if e.Stack != nil {
+		return e.Stack.String()
+	}
+	return formatStackTrace(e.StackTrace)
 }
 
-// captureStackTrace captures the stack trace using github.com/pkg/errors
-func captureStackTrace(message string) string {
-	return fmt.Sprintf("%+v", errors.WithStack(errors.New(message)))
+// Frames returns the structured stack frames, innermost first
+func (e *ErrorService) Frames() []runtime.Frame {
+	return e.Stack.Frames()
+}
+
+// newErrorService creates an ErrorService and captures the caller's stack
+func newErrorService(statusCode int, code ErrorCode, message string) *ErrorService {
+	stack := captureStack()
+	errService := &ErrorService{
+		StatusText:   http.StatusText(statusCode),
+		StatusCode:   statusCode,
+		ErrorCode:    string(code),
+		ErrorMessage: message,
+		Stack:        stack,
+	}
+	if stack != nil && stack.cfg.Mode == StackEager {
+		errService.StackTrace = stack.String()
+	}
+	return errService
 }
 
 func formatStackTrace(trace string) string {
@@ -92,98 +113,42 @@ func GetStatusCode(errCode Status) int {
 
 // CreateError creates a new ErrorService instance with the given code and message
 func CreateError(status Status, code ErrorCode, message string) *ErrorService {
-	statusCode := GetStatusCode(status)
-	stackTrace := captureStackTrace(message)
-	return &ErrorService{
-		StatusText:   http.StatusText(statusCode),
-		StatusCode:   statusCode,
-		ErrorCode:    string(code),
-		ErrorMessage: message,
-		StackTrace:   stackTrace,
-	}
+	return newErrorService(GetStatusCode(status), code, message)
 }
 
 // CreateInternalError creates a new ErrorService instance for internal server errors
 func CreateInternalError() *ErrorService {
-	statusCode := GetStatusCode(INTERNAL_SERVER_ERROR)
-	stackTrace := captureStackTrace("Internal Server Error")
-	return &ErrorService{
-		StatusText:   http.StatusText(statusCode),
-		StatusCode:   statusCode,
-		ErrorCode:    string(SystemError),
-		ErrorMessage: "Internal Server Error",
-		StackTrace:   stackTrace,
-	}
+	return newErrorService(GetStatusCode(INTERNAL_SERVER_ERROR), SystemError, "Internal Server Error")
 }
 
 // CreateServiceUnavailableError creates a new ErrorService instance for service unavailable errors
 func CreateServiceUnavailableError(message string) *ErrorService {
-	statusCode := GetStatusCode(SERVICE_UNAVAILABLE)
-	stackTrace := captureStackTrace(message)
-	return &ErrorService{
-		StatusText:   http.StatusText(statusCode),
-		StatusCode:   statusCode,
-		ErrorCode:    string(SystemError),
-		ErrorMessage: message,
-		StackTrace:   stackTrace,
-	}
+	return newErrorService(GetStatusCode(SERVICE_UNAVAILABLE), SystemError, message)
 }
 
 // CreateBadRequestError creates a new ErrorService instance for bad request errors
 func CreateBadRequestError(code ErrorCode, message string) *ErrorService {
-	statusCode := GetStatusCode(BAD_REQUEST)
-	stackTrace := captureStackTrace(message)
-	return &ErrorService{
-		StatusText:   http.StatusText(statusCode),
-		StatusCode:   statusCode,
-		ErrorCode:    string(code),
-		ErrorMessage: message,
-		StackTrace:   stackTrace,
-	}
+	return newErrorService(GetStatusCode(BAD_REQUEST), code, message)
 }
 
 // CreateNotFoundError creates a new ErrorService instance for not found errors
 func CreateNotFoundError(code ErrorCode, message string) *ErrorService {
-	statusCode := GetStatusCode(NOT_FOUND)
-	stackTrace := captureStackTrace(message)
-	return &ErrorService{
-		StatusText:   http.StatusText(statusCode),
-		StatusCode:   statusCode,
-		ErrorCode:    string(code),
-		ErrorMessage: message,
-		StackTrace:   stackTrace,
-	}
+	return newErrorService(GetStatusCode(NOT_FOUND), code, message)
 }
 
 // CreateUnprocessableEntityError creates a new ErrorService instance for unprocessable entity errors
 func CreateUnprocessableEntityError(code ErrorCode, message string) *ErrorService {
-	statusCode := GetStatusCode(UNPROCESSABLE_ENTITY)
-	stackTrace := captureStackTrace(message)
-	return &ErrorService{
-		StatusText:   http.StatusText(statusCode),
-		StatusCode:   statusCode,
-		ErrorCode:    string(code),
-		ErrorMessage: message,
-		StackTrace:   stackTrace,
-	}
+	return newErrorService(GetStatusCode(UNPROCESSABLE_ENTITY), code, message)
 }
 
 func CreateNoContentError(code ErrorCode, message string) *ErrorService {
-	statusCode := GetStatusCode(NO_CONTENT)
-	stackTrace := captureStackTrace(message)
-	return &ErrorService{
-		StatusText:   http.StatusText(statusCode),
-		StatusCode:   statusCode,
-		ErrorCode:    string(code),
-		ErrorMessage: message,
-		StackTrace:   stackTrace,
-	}
+	return newErrorService(GetStatusCode(NO_CONTENT), code, message)
 }
 
-// ExtractErrorDetails extracts the details from ErrorService
+// ExtractErrorDetails extracts the details from the first ErrorService in err's chain
 func ExtractErrorDetails(err error) (res *ErrorService) {
-	if err, ok := err.(*ErrorService); ok {
-		return err
+	if errors.As(err, &res) {
+		return res
 	}
-	return res
+	return nil
 }
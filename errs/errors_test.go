@@ -0,0 +1,95 @@
+package errs_test
+
+import (
+	"strings"
+	"testing"
+
+	"otel-library/errs"
+)
+
+// withStackConfig applies cfg for the duration of the test.
+func withStackConfig(t *testing.T, cfg errs.StackConfig) {
+	t.Helper()
+	prev := errs.CurrentStackConfig()
+	errs.SetStackConfig(cfg)
+	t.Cleanup(func() { errs.SetStackConfig(prev) })
+}
+
+func newTestError() *errs.ErrorService {
+	return errs.CreateNotFoundError(errs.NotFound, "missing")
+}
+
+func TestStackEagerIsDefault(t *testing.T) {
+	withStackConfig(t, errs.StackConfig{})
+
+	if err := newTestError(); err.StackTrace == "" || err.StackTrace != err.GetStackTrace() {
+		t.Errorf("StackTrace = %q, want the rendered stack filled by the eager default", err.StackTrace)
+	}
+}
+
+func TestStackLazyLeavesStackTraceEmpty(t *testing.T) {
+	withStackConfig(t, errs.StackConfig{Mode: errs.StackLazy})
+
+	err := newTestError()
+	if err.StackTrace != "" {
+		t.Errorf("StackTrace = %q, want it left empty in lazy mode", err.StackTrace)
+	}
+	if err.GetStackTrace() == "" {
+		t.Error("GetStackTrace is empty, want the lazily resolved stack")
+	}
+}
+
+func TestStackDisabledCapturesNothing(t *testing.T) {
+	withStackConfig(t, errs.StackConfig{Mode: errs.StackDisabled})
+
+	if err := newTestError(); err.Stack != nil || len(err.Frames()) != 0 {
+		t.Errorf("got stack %v, want none", err.Frames())
+	}
+}
+
+func TestStackFramesSkipErrsAndLibraries(t *testing.T) {
+	withStackConfig(t, errs.StackConfig{})
+
+	frames := newTestError().Frames()
+	if len(frames) == 0 {
+		t.Fatal("no frames captured")
+	}
+	if frames[0].Function != "errs_test.newTestError" || frames[0].File != "errs/errors_test.go" {
+		t.Errorf("innermost frame = %s %s, want errs_test.newTestError in errs/errors_test.go", frames[0].Function, frames[0].File)
+	}
+	for _, f := range frames {
+		if strings.HasPrefix(f.Function, "testing.") || strings.HasPrefix(f.Function, "runtime.") {
+			t.Errorf("library frame %s was kept", f.Function)
+		}
+	}
+}
+
+func TestStackKeepLibraryFrames(t *testing.T) {
+	withStackConfig(t, errs.StackConfig{KeepLibraryFrames: true})
+
+	var found bool
+	for _, f := range newTestError().Frames() {
+		if f.Function == "testing.tRunner" {
+			found = f.File == "testing/testing.go"
+		}
+	}
+	if !found {
+		t.Error("testing.tRunner frame missing or not trimmed to testing/testing.go")
+	}
+}
+
+func TestStackDepth(t *testing.T) {
+	withStackConfig(t, errs.StackConfig{Depth: 1, KeepLibraryFrames: true})
+
+	if frames := newTestError().Frames(); len(frames) != 1 {
+		t.Errorf("got %d frames, want 1", len(frames))
+	}
+}
+
+func TestStackStringUsesConfiguredFormat(t *testing.T) {
+	withStackConfig(t, errs.StackConfig{Format: errs.StackFormatJava})
+
+	if got := newTestError().GetStackTrace(); !strings.HasPrefix(got, "\tat errs_test.newTestError(errors_test.go:") {
+		t.Errorf("GetStackTrace() = %q, want a Java-style stack", got)
+	}
+}
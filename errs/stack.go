@@ -0,0 +1,280 @@
+package errs
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// StackMode controls when ErrorService stack traces are captured and resolved.
+type StackMode int
+
+const (
+	// StackEager, the default, captures and resolves the stack when the error is created and
+	// fills the StackTrace string field.
+	StackEager StackMode = iota
+	// StackLazy only records program counters when the error is created; frames are resolved
+	// the first time the stack is read. StackTrace is left empty, use GetStackTrace. Meant for
+	// hot paths that create many errors whose stacks are rarely read.
+	StackLazy
+	// StackDisabled captures no stack at all, for hot paths.
+	StackDisabled
+)
+
+// StackFormat selects how stack traces are rendered by GetStackTrace.
+type StackFormat int
+
+const (
+	// StackFormatGo renders frames like a Go panic: the function, then the indented file:line.
+	StackFormatGo StackFormat = iota
+	// StackFormatJava renders one "at function(file:line)" line per frame.
+	StackFormatJava
+	// StackFormatJSON renders a JSON array of {"function","file","line"} objects.
+	StackFormatJSON
+)
+
+// StackConfig configures stack capture for every ErrorService created afterwards.
+type StackConfig struct {
+	Mode   StackMode
+	Format StackFormat
+	// Depth is the maximum number of frames kept. Zero uses DefaultStackDepth.
+	Depth int
+	// SkipPackages lists packages whose frames are dropped. The errs package is always skipped.
+	SkipPackages []string
+	// KeepLibraryFrames keeps frames of the standard library and of dependencies, which are
+	// dropped by default so that stacks only show application code.
+	KeepLibraryFrames bool
+	// ModulePath is trimmed from function names and file paths. Empty uses the main module
+	// path from the build info.
+	ModulePath string
+}
+
+// DefaultStackDepth is the number of frames kept when StackConfig.Depth is zero.
+const DefaultStackDepth = 32
+
+var (
+	stackConfig atomic.Pointer[StackConfig]
+
+	// errsPackage is the import path of this package, used to skip its own frames.
+	errsPackage = packageName(runtime.FuncForPC(reflect.ValueOf(GetStatusCode).Pointer()).Name())
+
+	mainModulePath = sync.OnceValue(func() string {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			return info.Main.Path
+		}
+		return ""
+	})
+)
+
+// SetStackConfig replaces the stack configuration. It is safe for concurrent use.
+func SetStackConfig(cfg StackConfig) {
+	stackConfig.Store(&cfg)
+}
+
+// CurrentStackConfig returns the stack configuration in use.
+func CurrentStackConfig() StackConfig {
+	if cfg := stackConfig.Load(); cfg != nil {
+		return *cfg
+	}
+	return StackConfig{}
+}
+
+// Stack is a captured call stack.
+type Stack struct {
+	pcs    []uintptr
+	cfg    StackConfig
+	once   sync.Once
+	frames []runtime.Frame
+}
+
+// captureStack records the caller's stack according to the current configuration, or returns
+// nil when stacks are disabled.
+func captureStack() *Stack {
+	cfg := CurrentStackConfig()
+	if cfg.Mode == StackDisabled {
+		return nil
+	}
+	if cfg.Depth <= 0 {
+		cfg.Depth = DefaultStackDepth
+	}
+
+	// Frames of this package and of libraries are filtered out later, so over-capture to keep
+	// Depth application frames.
+	pcs := make([]uintptr, 2*cfg.Depth+8)
+	n := runtime.Callers(2, pcs)
+	s := &Stack{pcs: pcs[:n], cfg: cfg}
+	if cfg.Mode == StackEager {
+		s.Frames()
+	}
+	return s
+}
+
+// Frames returns the resolved frames, innermost first, without skipped packages and with the
+// module path trimmed.
+func (s *Stack) Frames() []runtime.Frame {
+	if s == nil {
+		return nil
+	}
+	s.once.Do(func() {
+		modulePath := s.cfg.ModulePath
+		if modulePath == "" {
+			modulePath = mainModulePath()
+		}
+
+		frames := runtime.CallersFrames(s.pcs)
+		for len(s.frames) < s.cfg.Depth {
+			frame, more := frames.Next()
+			if !s.skip(frame, modulePath) {
+				frame.File = trimFilePath(frame.File, frame.Function, modulePath)
+				frame.Function = trimModulePath(frame.Function, modulePath)
+				s.frames = append(s.frames, frame)
+			}
+			if !more {
+				break
+			}
+		}
+		s.pcs = nil
+	})
+	return s.frames
+}
+
+// skip reports whether frame belongs to a skipped package, to a library unless library frames
+// are kept, or to the runtime's goroutine entry.
+func (s *Stack) skip(frame runtime.Frame, modulePath string) bool {
+	pkg := packageName(frame.Function)
+	if pkg == errsPackage || frame.Function == "runtime.goexit" {
+		return true
+	}
+	if !s.cfg.KeepLibraryFrames && isLibrary(pkg, frame.File, modulePath) {
+		return true
+	}
+	for _, skipped := range s.cfg.SkipPackages {
+		if pkg == skipped {
+			return true
+		}
+	}
+	return false
+}
+
+// Format renders the stack in the given format.
+func (s *Stack) Format(format StackFormat) string {
+	frames := s.Frames()
+	var b strings.Builder
+	switch format {
+	case StackFormatJava:
+		for _, f := range frames {
+			b.WriteString("\tat ")
+			b.WriteString(f.Function)
+			b.WriteString("(")
+			b.WriteString(path.Base(f.File))
+			b.WriteString(":")
+			b.WriteString(strconv.Itoa(f.Line))
+			b.WriteString(")\n")
+		}
+	case StackFormatJSON:
+		type jsonFrame struct {
+			Function string `json:"function"`
+			File     string `json:"file"`
+			Line     int    `json:"line"`
+		}
+		out := make([]jsonFrame, len(frames))
+		for i, f := range frames {
+			out[i] = jsonFrame{Function: f.Function, File: f.File, Line: f.Line}
+		}
+		data, _ := json.Marshal(out)
+		return string(data)
+	default:
+		for _, f := range frames {
+			b.WriteString(f.Function)
+			b.WriteString("\n\t")
+			b.WriteString(f.File)
+			b.WriteString(":")
+			b.WriteString(strconv.Itoa(f.Line))
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// String renders the stack in the configured format.
+func (s *Stack) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.Format(s.cfg.Format)
+}
+
+// packageName returns the import path of a fully qualified function name such as
+// "example.com/mod/pkg.(*T).Method". Dots in the last path element are escaped as "%2e" in
+// function names, e.g. "gopkg.in/yaml%2ev3.Unmarshal", and are unescaped in the result.
+func packageName(function string) string {
+	slash := strings.LastIndex(function, "/")
+	pkg := function
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		pkg = function[:slash+1+dot]
+	}
+	if unescaped, err := url.PathUnescape(pkg); err == nil {
+		return unescaped
+	}
+	return pkg
+}
+
+// isStdlib reports whether pkg belongs to the standard library, whose import paths have no dot
+// in their first element.
+func isStdlib(pkg string) bool {
+	first, _, _ := strings.Cut(pkg, "/")
+	return pkg != "main" && !strings.Contains(first, ".")
+}
+
+// isLibrary reports whether a frame of pkg, compiled from file, is library code rather than
+// part of the application: anything outside modulePath when it is known, otherwise the
+// standard library and packages from the module cache or a vendor directory.
+func isLibrary(pkg, file, modulePath string) bool {
+	if pkg == "main" {
+		return false
+	}
+	if modulePath != "" {
+		return pkg != modulePath && !strings.HasPrefix(pkg, modulePath+"/")
+	}
+	return isStdlib(pkg) || strings.Contains(file, "/pkg/mod/") || strings.Contains(file, "/vendor/")
+}
+
+// trimModulePath strips "<modulePath>/" from a function name.
+func trimModulePath(function, modulePath string) string {
+	if modulePath == "" {
+		return function
+	}
+	return strings.TrimPrefix(function, modulePath+"/")
+}
+
+// trimFilePath makes file paths relative to the module root, the module cache or GOROOT. Files
+// of the main package are reduced to their base name, as its directory is not known.
+func trimFilePath(file, function, modulePath string) string {
+	pkg := packageName(function)
+	switch {
+	case pkg == "main":
+		return path.Base(file)
+	case modulePath != "" && pkg == modulePath:
+		return path.Base(file)
+	case modulePath != "" && strings.HasPrefix(pkg, modulePath+"/"):
+		// External test packages live in the directory of the package they test.
+		dir := strings.TrimSuffix(strings.TrimPrefix(pkg, modulePath+"/"), "_test")
+		return dir + "/" + path.Base(file)
+	}
+	if i := strings.LastIndex(file, "/pkg/mod/"); i >= 0 {
+		return file[i+len("/pkg/mod/"):]
+	}
+	if isStdlib(pkg) {
+		if i := strings.LastIndex(file, "/src/"); i >= 0 {
+			return file[i+len("/src/"):]
+		}
+	}
+	return file
+}
@@ -0,0 +1,107 @@
+package errs
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+)
+
+// resolvedStack returns a stack whose frames are already resolved to frames.
+func resolvedStack(frames []runtime.Frame) *Stack {
+	s := &Stack{frames: frames}
+	s.once.Do(func() {})
+	return s
+}
+
+var testFrames = []runtime.Frame{
+	{Function: "api.(*Handler).Get", File: "api/handler.go", Line: 42},
+	{Function: "main.main", File: "main.go", Line: 7},
+}
+
+func TestStackFormatGo(t *testing.T) {
+	want := "api.(*Handler).Get\n\tapi/handler.go:42\nmain.main\n\tmain.go:7"
+	if got := resolvedStack(testFrames).Format(StackFormatGo); got != want {
+		t.Errorf("Format(StackFormatGo) = %q, want %q", got, want)
+	}
+}
+
+func TestStackFormatJava(t *testing.T) {
+	want := "\tat api.(*Handler).Get(handler.go:42)\n\tat main.main(main.go:7)"
+	if got := resolvedStack(testFrames).Format(StackFormatJava); got != want {
+		t.Errorf("Format(StackFormatJava) = %q, want %q", got, want)
+	}
+}
+
+func TestStackFormatJSON(t *testing.T) {
+	var got []struct {
+		Function string `json:"function"`
+		File     string `json:"file"`
+		Line     int    `json:"line"`
+	}
+	if err := json.Unmarshal([]byte(resolvedStack(testFrames).Format(StackFormatJSON)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Function != "api.(*Handler).Get" || got[0].File != "api/handler.go" || got[0].Line != 42 {
+		t.Errorf("Format(StackFormatJSON) = %+v", got)
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	tests := map[string]string{
+		"example.com/mod/pkg.(*T).Method": "example.com/mod/pkg",
+		"gopkg.in/yaml%2ev3.Unmarshal":    "gopkg.in/yaml.v3",
+		"net/http.HandlerFunc.ServeHTTP":  "net/http",
+		"main.main.func1":                 "main",
+		"runtime.goexit":                  "runtime",
+	}
+	for function, want := range tests {
+		if got := packageName(function); got != want {
+			t.Errorf("packageName(%q) = %q, want %q", function, got, want)
+		}
+	}
+}
+
+func TestSkipPackagesMatchesDottedPaths(t *testing.T) {
+	s := &Stack{cfg: StackConfig{SkipPackages: []string{"gopkg.in/yaml.v3"}, KeepLibraryFrames: true}}
+	if !s.skip(runtime.Frame{Function: "gopkg.in/yaml%2ev3.Unmarshal"}, "example.com/app") {
+		t.Error("frame of gopkg.in/yaml.v3 was not skipped")
+	}
+}
+
+func TestIsLibrary(t *testing.T) {
+	tests := []struct {
+		pkg, file, modulePath string
+		want                  bool
+	}{
+		{"example.com/app/api", "/src/app/api/h.go", "example.com/app", false},
+		{"example.com/app", "/src/app/app.go", "example.com/app", false},
+		{"main", "/src/app/main.go", "example.com/app", false},
+		{"github.com/gin-gonic/gin", "/go/pkg/mod/github.com/gin-gonic/gin@v1.9.1/gin.go", "example.com/app", true},
+		{"net/http", "/usr/local/go/src/net/http/server.go", "example.com/app", true},
+		{"net/http", "/usr/local/go/src/net/http/server.go", "", true},
+		{"github.com/gin-gonic/gin", "/go/pkg/mod/github.com/gin-gonic/gin@v1.9.1/gin.go", "", true},
+		{"example.com/app/api", "/src/app/api/h.go", "", false},
+	}
+	for _, tt := range tests {
+		if got := isLibrary(tt.pkg, tt.file, tt.modulePath); got != tt.want {
+			t.Errorf("isLibrary(%q, %q, %q) = %v, want %v", tt.pkg, tt.file, tt.modulePath, got, tt.want)
+		}
+	}
+}
+
+func TestTrimFilePath(t *testing.T) {
+	tests := []struct {
+		file, function, want string
+	}{
+		{"/home/dev/src/app/api/handler.go", "example.com/app/api.Get", "api/handler.go"},
+		{"/home/dev/src/app/api/handler_test.go", "example.com/app/api_test.TestGet", "api/handler_test.go"},
+		{"/home/dev/src/app/cmd/server/main.go", "main.main", "main.go"},
+		{"/go/pkg/mod/gopkg.in/yaml.v3@v3.0.1/decode.go", "gopkg.in/yaml%2ev3.Unmarshal", "gopkg.in/yaml.v3@v3.0.1/decode.go"},
+		{"/usr/local/go/src/net/http/server.go", "net/http.HandlerFunc.ServeHTTP", "net/http/server.go"},
+	}
+	for _, tt := range tests {
+		if got := trimFilePath(tt.file, tt.function, "example.com/app"); got != tt.want {
+			t.Errorf("trimFilePath(%q, %q) = %q, want %q", tt.file, tt.function, got, tt.want)
+		}
+	}
+}
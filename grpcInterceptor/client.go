@@ -0,0 +1,150 @@
+package apw_grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"otel-library/otelBuilder"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns an interceptor that wraps each call in a client span and
+// injects its context into the outgoing metadata.
+func UnaryClientInterceptor(o *otelBuilder.Otel, opts ...Option) grpc.UnaryClientInterceptor {
+	i := newInstrumentation(o, trace.SpanKindClient, opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if !i.instrumented(method) {
+			return i.clientError(invoker(i.inject(ctx), method, req, reply, cc, callOpts...))
+		}
+
+		start := time.Now()
+		ctx, span := i.start(ctx, method, targetAttributes(cc)...)
+		err := invoker(i.inject(ctx), method, req, reply, cc, callOpts...)
+		i.end(ctx, span, method, start, err)
+		return i.clientError(err)
+	}
+}
+
+// StreamClientInterceptor returns the streaming counterpart of UnaryClientInterceptor. The span
+// ends when the stream fails, the server closes it, the call's context is done, or, for calls
+// without server streaming, the response has been received.
+func StreamClientInterceptor(o *otelBuilder.Otel, opts ...Option) grpc.StreamClientInterceptor {
+	i := newInstrumentation(o, trace.SpanKindClient, opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if !i.instrumented(method) {
+			cs, err := streamer(i.inject(ctx), desc, cc, method, callOpts...)
+			return cs, i.clientError(err)
+		}
+
+		start := time.Now()
+		ctx, span := i.start(ctx, method, targetAttributes(cc)...)
+		cs, err := streamer(i.inject(ctx), desc, cc, method, callOpts...)
+		if err != nil {
+			i.end(ctx, span, method, start, err)
+			return nil, i.clientError(err)
+		}
+		s := &clientStream{
+			ClientStream: cs,
+			finish: func(err error) {
+				i.end(ctx, span, method, start, err)
+			},
+			done:          make(chan struct{}),
+			serverStreams: desc.ServerStreams,
+			convert:       i.clientError,
+		}
+		// Callers that cancel the call instead of draining the stream never see its end.
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.end(ctx.Err())
+			case <-s.done:
+			}
+		}()
+		return s, nil
+	}
+}
+
+// clientStream ends the span of a streaming call once the call is over.
+type clientStream struct {
+	grpc.ClientStream
+	once          sync.Once
+	finish        func(err error)
+	done          chan struct{}
+	serverStreams bool
+	convert       func(err error) error
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.end(err)
+	}
+	return s.convert(err)
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	case !s.serverStreams:
+		s.end(nil)
+	}
+	return s.convert(err)
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.end(err)
+	}
+	return md, s.convert(err)
+}
+
+func (s *clientStream) end(err error) {
+	s.once.Do(func() {
+		s.finish(err)
+		close(s.done)
+	})
+}
+
+// inject adds the trace context of ctx to the outgoing metadata.
+func (i *instrumentation) inject(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	i.tracing.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// clientError converts status errors with ErrorFromStatus when WithErrorServices is set.
+// io.EOF, which marks the end of a stream, is returned unchanged.
+func (i *instrumentation) clientError(err error) error {
+	if err == nil || !i.cfg.errorServices || errors.Is(err, io.EOF) {
+		return err
+	}
+	st, _ := status.FromError(err)
+	return ErrorFromStatus(st)
+}
+
+// targetAttributes returns the address the client connection dials.
+func targetAttributes(cc *grpc.ClientConn) []attribute.KeyValue {
+	if cc == nil || cc.Target() == "" {
+		return nil
+	}
+	return []attribute.KeyValue{semconv.ServerAddress(cc.Target())}
+}
@@ -0,0 +1,162 @@
+// Package apw_grpc provides gRPC server and client interceptors that create spans with rpc.*
+// attributes, propagate trace context through metadata and record rpc.server.duration and
+// rpc.client.duration.
+package apw_grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	apw_metrics "otel-library/metrics"
+	"otel-library/otelBuilder"
+	apw_tracing "otel-library/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// scopeName is the instrumentation scope of the spans and metrics recorded by this package.
+const scopeName = "otel-library/grpc"
+
+type config struct {
+	filter        func(fullMethod string) bool
+	errorServices bool
+}
+
+// Option configures the interceptors.
+type Option func(*config)
+
+// WithFilter skips instrumentation of calls for which filter returns false, for example health
+// checks. The context is still propagated.
+func WithFilter(filter func(fullMethod string) bool) Option {
+	return func(c *config) {
+		c.filter = filter
+	}
+}
+
+// WithErrorServices makes the client interceptors return errors as *errs.ErrorService, converted
+// with ErrorFromStatus, instead of status errors.
+func WithErrorServices() Option {
+	return func(c *config) {
+		c.errorServices = true
+	}
+}
+
+// instrumentation holds the tracer and duration histogram shared by one side's interceptors.
+type instrumentation struct {
+	tracing  apw_tracing.OtelTracing
+	duration metric.Int64Histogram
+	kind     trace.SpanKind
+	cfg      config
+}
+
+func newInstrumentation(o *otelBuilder.Otel, kind trace.SpanKind, opts []Option) *instrumentation {
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	name, description := "rpc.client.duration", "Duration of outbound RPCs"
+	if kind == trace.SpanKindServer {
+		name, description = "rpc.server.duration", "Duration of inbound RPCs"
+	}
+	duration, err := createHistogram(o.Meter(scopeName, ""), name, description)
+	if err != nil {
+		o.Logs.Warnf("grpc: cannot create %s histogram: %v", name, err)
+	}
+	return &instrumentation{
+		tracing:  o.Tracer(scopeName, ""),
+		duration: duration,
+		kind:     kind,
+		cfg:      cfg,
+	}
+}
+
+func createHistogram(m apw_metrics.OtelMetric, name, description string) (metric.Int64Histogram, error) {
+	return m.CreateHistogram(name, metric.WithDescription(description), metric.WithUnit("ms"))
+}
+
+// instrumented reports whether calls to fullMethod create spans and metrics.
+func (i *instrumentation) instrumented(fullMethod string) bool {
+	return i.cfg.filter == nil || i.cfg.filter(fullMethod)
+}
+
+// start starts the span of a call to fullMethod.
+func (i *instrumentation) start(ctx context.Context, fullMethod string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(methodAttributes(fullMethod), attrs...)
+	return i.tracing.StartSpan(ctx, strings.TrimPrefix(fullMethod, "/"),
+		trace.WithSpanKind(i.kind),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end records the outcome of the call on the span and duration histogram, and ends the span.
+// Errors are only recorded on the span; logging them is left to the caller.
+func (i *instrumentation) end(ctx context.Context, span trace.Span, fullMethod string, start time.Time, err error) {
+	code := StatusFromError(err).Code()
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if err != nil {
+		span.RecordError(err)
+	}
+	if isSpanError(code, i.kind) {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	i.tracing.EndSpan(span)
+
+	if i.duration != nil {
+		attrs := append(methodAttributes(fullMethod), semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		i.duration.Record(ctx, time.Since(start).Milliseconds(), metric.WithAttributes(attrs...))
+	}
+}
+
+// isSpanError follows the gRPC semantic conventions: every non-OK code is an error on client
+// spans, while server spans only treat codes that indicate a server fault as errors.
+func isSpanError(code grpccodes.Code, kind trace.SpanKind) bool {
+	if kind != trace.SpanKindServer {
+		return code != grpccodes.OK
+	}
+	switch code {
+	case grpccodes.Unknown, grpccodes.DeadlineExceeded, grpccodes.Unimplemented,
+		grpccodes.Internal, grpccodes.Unavailable, grpccodes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// methodAttributes splits a full method name such as "/pkg.Service/Method" into rpc attributes.
+func methodAttributes(fullMethod string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{semconv.RPCSystemGRPC}
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return append(attrs, semconv.RPCMethod(fullMethod))
+	}
+	return append(attrs, semconv.RPCService(service), semconv.RPCMethod(method))
+}
+
+// metadataCarrier adapts gRPC metadata to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
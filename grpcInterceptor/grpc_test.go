@@ -0,0 +1,320 @@
+package apw_grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"otel-library/errs"
+	apw_logging "otel-library/logs"
+	apw_metrics "otel-library/metrics"
+	"otel-library/otelBuilder"
+	apw_tracing "otel-library/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const (
+	unaryMethod  = "/test.Echo/Unary"
+	streamMethod = "/test.Echo/Stream"
+)
+
+// echoDesc describes a hand-written echo service so the tests need no generated code. A request
+// of "not found" fails with a 404 *errs.ErrorService and "handled" with a 200 one.
+var echoDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Unary",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+			in := new(wrapperspb.StringValue)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return echo(ctx, in)
+			}
+			return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: unaryMethod},
+				func(ctx context.Context, req any) (any, error) { return echo(ctx, req.(*wrapperspb.StringValue)) })
+		},
+	}},
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		ServerStreams: true,
+		ClientStreams: true,
+		Handler: func(_ any, stream grpc.ServerStream) error {
+			for {
+				in := new(wrapperspb.StringValue)
+				if err := stream.RecvMsg(in); errors.Is(err, io.EOF) {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				out, err := echo(stream.Context(), in)
+				if err != nil {
+					return err
+				}
+				if err := stream.SendMsg(out); err != nil {
+					return err
+				}
+			}
+		},
+	}},
+}
+
+func echo(_ context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	switch in.GetValue() {
+	case "not found":
+		return nil, errs.CreateNotFoundError(errs.NotFound, "missing")
+	case "handled":
+		return nil, &errs.ErrorService{StatusCode: http.StatusOK, ErrorCode: "HANDLED", ErrorMessage: "handled"}
+	}
+	return in, nil
+}
+
+type testEnv struct {
+	conn   *grpc.ClientConn
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+// newTestEnv serves echoDesc over bufconn with both server interceptors and returns a client
+// connection using both client interceptors, all recording into the same telemetry.
+func newTestEnv(t *testing.T, clientOpts ...Option) *testEnv {
+	t.Helper()
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	l := apw_logging.NewNoopLogging()
+	o := otelBuilder.NewOtel(
+		apw_tracing.NewTracing(tp.Tracer("test"), l, apw_tracing.WithTracerProvider(tp)),
+		apw_metrics.NewMetric(mp.Meter("test"), apw_metrics.WithMeterProvider(mp)),
+		l,
+	)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(o)),
+		grpc.StreamInterceptor(StreamServerInterceptor(o)),
+	)
+	srv.RegisterService(&echoDesc, nil)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(o, clientOpts...)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(o, clientOpts...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testEnv{conn: conn, spans: spans, reader: reader}
+}
+
+// span waits for the ended span of the given kind, since server spans end after the client
+// has its response.
+func (e *testEnv) span(t *testing.T, kind trace.SpanKind) sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, span := range e.spans.Ended() {
+			if span.SpanKind() == kind {
+				return span
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no %v span ended", kind)
+	return nil
+}
+
+func grpcStatusCode(span sdktrace.ReadOnlySpan) (int64, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == semconv.RPCGRPCStatusCodeKey {
+			return attr.Value.AsInt64(), true
+		}
+	}
+	return 0, false
+}
+
+func (e *testEnv) unary(ctx context.Context, value string) (*wrapperspb.StringValue, error) {
+	out := new(wrapperspb.StringValue)
+	err := e.conn.Invoke(ctx, unaryMethod, wrapperspb.String(value), out)
+	return out, err
+}
+
+func TestUnaryPropagatesTraceAndRecordsSpans(t *testing.T) {
+	env := newTestEnv(t)
+
+	out, err := env.unary(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.GetValue() != "hello" {
+		t.Errorf("got %q, want hello", out.GetValue())
+	}
+
+	client := env.span(t, trace.SpanKindClient)
+	server := env.span(t, trace.SpanKindServer)
+	if client.Name() != "test.Echo/Unary" || server.Name() != "test.Echo/Unary" {
+		t.Errorf("span names = %q, %q, want test.Echo/Unary", client.Name(), server.Name())
+	}
+	if server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Error("server span is not a child of the client span")
+	}
+	if code, ok := grpcStatusCode(server); !ok || code != int64(grpccodes.OK) {
+		t.Errorf("server %s = %d, want OK", semconv.RPCGRPCStatusCodeKey, code)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := env.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = true
+		}
+	}
+	if !found["rpc.server.duration"] || !found["rpc.client.duration"] {
+		t.Errorf("recorded metrics %v, want rpc.server.duration and rpc.client.duration", found)
+	}
+}
+
+func TestUnaryErrorServiceRoundTrip(t *testing.T) {
+	env := newTestEnv(t, WithErrorServices())
+
+	_, err := env.unary(context.Background(), "not found")
+	var errService *errs.ErrorService
+	if !errors.As(err, &errService) {
+		t.Fatalf("got %T %v, want *errs.ErrorService", err, err)
+	}
+	if errService.StatusCode != http.StatusNotFound || errService.ErrorCode != string(errs.NotFound) {
+		t.Errorf("got status %d code %q, want 404 %q", errService.StatusCode, errService.ErrorCode, errs.NotFound)
+	}
+
+	// NotFound is the caller's fault: an error on the client span only.
+	if client := env.span(t, trace.SpanKindClient); client.Status().Code != codes.Error {
+		t.Errorf("client span status = %v, want Error", client.Status().Code)
+	}
+	server := env.span(t, trace.SpanKindServer)
+	if server.Status().Code == codes.Error {
+		t.Error("server span status = Error, want unset for NotFound")
+	}
+	if code, _ := grpcStatusCode(server); code != int64(grpccodes.NotFound) {
+		t.Errorf("server %s = %d, want NotFound", semconv.RPCGRPCStatusCodeKey, code)
+	}
+	if len(server.Events()) != 1 || server.Events()[0].Name != semconv.ExceptionEventName {
+		t.Errorf("server span events = %v, want one exception event", server.Events())
+	}
+}
+
+func TestUnaryNonErrorStatusServiceStillFails(t *testing.T) {
+	env := newTestEnv(t)
+
+	_, err := env.unary(context.Background(), "handled")
+	if status.Code(err) != grpccodes.Unknown {
+		t.Fatalf("got %v, want an Unknown status error", err)
+	}
+	if restored := ErrorFromStatus(status.Convert(err)); restored.(*errs.ErrorService).StatusCode != http.StatusOK {
+		t.Errorf("restored %v, want the original 200 status code", restored)
+	}
+}
+
+func TestStreamRecordsOneSpanPerSide(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	stream, err := env.conn.NewStream(ctx, &echoDesc.Streams[0], streamMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"a", "b"} {
+		if err := stream.SendMsg(wrapperspb.String(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	var received []string
+	for {
+		out := new(wrapperspb.StringValue)
+		if err := stream.RecvMsg(out); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		received = append(received, out.GetValue())
+	}
+	if len(received) != 2 {
+		t.Fatalf("received %v, want two echoes", received)
+	}
+
+	client := env.span(t, trace.SpanKindClient)
+	server := env.span(t, trace.SpanKindServer)
+	if server.Parent().SpanID() != client.SpanContext().SpanID() {
+		t.Error("server span is not a child of the client span")
+	}
+	if got := len(env.spans.Ended()); got != 2 {
+		t.Errorf("got %d ended spans, want 2", got)
+	}
+}
+
+func TestStreamErrorStatusReachesClient(t *testing.T) {
+	env := newTestEnv(t)
+
+	stream, err := env.conn.NewStream(context.Background(), &echoDesc.Streams[0], streamMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(wrapperspb.String("not found")); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.RecvMsg(new(wrapperspb.StringValue)); status.Code(err) != grpccodes.NotFound {
+		t.Fatalf("RecvMsg returned %v, want NotFound", err)
+	}
+	if client := env.span(t, trace.SpanKindClient); client.Status().Code != codes.Error {
+		t.Errorf("client span status = %v, want Error", client.Status().Code)
+	}
+}
+
+func TestStreamSpanEndsWhenContextIsCanceled(t *testing.T) {
+	env := newTestEnv(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err := env.conn.NewStream(ctx, &echoDesc.Streams[0], streamMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.SendMsg(wrapperspb.String("a")); err != nil {
+		t.Fatal(err)
+	}
+	// Abandon the stream without draining it.
+	cancel()
+
+	client := env.span(t, trace.SpanKindClient)
+	if code, _ := grpcStatusCode(client); code != int64(grpccodes.Canceled) {
+		t.Errorf("client %s = %d, want Canceled", semconv.RPCGRPCStatusCodeKey, code)
+	}
+}
@@ -0,0 +1,92 @@
+package apw_grpc
+
+import (
+	"context"
+	"time"
+
+	"otel-library/otelBuilder"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns an interceptor that continues the caller's trace from the
+// incoming metadata and wraps each call in a server span. A handler error holding an
+// *errs.ErrorService is returned to the client as the matching status, see StatusFromError.
+func UnaryServerInterceptor(o *otelBuilder.Otel, opts ...Option) grpc.UnaryServerInterceptor {
+	i := newInstrumentation(o, trace.SpanKindServer, opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = i.extract(ctx)
+		if !i.instrumented(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		ctx, span := i.start(ctx, info.FullMethod, peerAttributes(ctx)...)
+		resp, err := handler(ctx, req)
+		i.end(ctx, span, info.FullMethod, start, err)
+		return resp, toStatusError(err)
+	}
+}
+
+// StreamServerInterceptor returns the streaming counterpart of UnaryServerInterceptor. The span
+// covers the whole stream.
+func StreamServerInterceptor(o *otelBuilder.Otel, opts ...Option) grpc.StreamServerInterceptor {
+	i := newInstrumentation(o, trace.SpanKindServer, opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := i.extract(ss.Context())
+		if !i.instrumented(info.FullMethod) {
+			return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		}
+
+		start := time.Now()
+		ctx, span := i.start(ctx, info.FullMethod, peerAttributes(ctx)...)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		i.end(ctx, span, info.FullMethod, start, err)
+		return toStatusError(err)
+	}
+}
+
+// serverStream replaces the context of a grpc.ServerStream with the one holding the span.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// extract continues the trace carried by the incoming metadata.
+func (i *instrumentation) extract(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return i.tracing.Extract(ctx, metadataCarrier(md))
+}
+
+// peerAttributes returns the address of the calling peer.
+func peerAttributes(ctx context.Context) []attribute.KeyValue {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return []attribute.KeyValue{semconv.NetworkPeerAddress(p.Addr.String())}
+	}
+	return nil
+}
+
+// toStatusError converts handler errors that are not already status errors with
+// StatusFromError, so that *errs.ErrorService codes reach the client.
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return StatusFromError(err).Err()
+}
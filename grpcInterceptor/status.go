@@ -0,0 +1,153 @@
+package apw_grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"otel-library/errs"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorInfoDomain identifies the ErrorInfo details added by StatusFromError.
+const errorInfoDomain = "otel-library/errs"
+
+// httpStatusKey is the ErrorInfo metadata key holding the original HTTP status code.
+const httpStatusKey = "http_status_code"
+
+// StatusFromError converts err into a gRPC status. Status errors are returned as is, context
+// errors map to Canceled and DeadlineExceeded, and an *errs.ErrorService in the chain maps its
+// HTTP status code to the matching gRPC code, carrying ErrorCode and StatusCode in an ErrorInfo
+// detail so that ErrorFromStatus can restore them. A nil error yields an OK status; any other
+// error yields a non-OK one, Unknown when its HTTP status code has no error counterpart.
+func StatusFromError(err error) *status.Status {
+	if err == nil {
+		return status.New(codes.OK, "")
+	}
+	if st, ok := status.FromError(err); ok {
+		return st
+	}
+
+	var errService *errs.ErrorService
+	switch {
+	case errors.As(err, &errService):
+		code := CodeFromHTTPStatus(errService.StatusCode)
+		if code == codes.OK {
+			code = codes.Unknown
+		}
+		st := status.New(code, errService.ErrorMessage)
+		detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   errService.ErrorCode,
+			Domain:   errorInfoDomain,
+			Metadata: map[string]string{httpStatusKey: strconv.Itoa(errService.StatusCode)},
+		})
+		if detailErr != nil {
+			return st
+		}
+		return detailed
+	case errors.Is(err, context.Canceled):
+		return status.New(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.New(codes.DeadlineExceeded, err.Error())
+	}
+	return status.New(codes.Unknown, err.Error())
+}
+
+// ErrorFromStatus converts a non-OK gRPC status into an *errs.ErrorService. The ErrorCode and
+// HTTP status code set by StatusFromError are restored; otherwise the HTTP status is derived
+// from the gRPC code with HTTPStatusFromCode. It returns nil for a nil or OK status.
+func ErrorFromStatus(st *status.Status) error {
+	if st == nil || st.Code() == codes.OK {
+		return nil
+	}
+
+	statusCode := HTTPStatusFromCode(st.Code())
+	errorCode := st.Code().String()
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != errorInfoDomain {
+			continue
+		}
+		errorCode = info.GetReason()
+		if code, err := strconv.Atoi(info.GetMetadata()[httpStatusKey]); err == nil {
+			statusCode = code
+		}
+	}
+
+	return &errs.ErrorService{
+		StatusText:   http.StatusText(statusCode),
+		StatusCode:   statusCode,
+		ErrorCode:    errorCode,
+		ErrorMessage: st.Message(),
+	}
+}
+
+// CodeFromHTTPStatus maps an HTTP status code to the closest gRPC code.
+func CodeFromHTTPStatus(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	switch {
+	case statusCode < 400:
+		return codes.OK
+	case statusCode < 500:
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
+
+// HTTPStatusFromCode maps a gRPC code to the closest HTTP status code.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
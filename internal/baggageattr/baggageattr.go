@@ -0,0 +1,30 @@
+// Package baggageattr converts allow-listed baggage members into attributes so that tracing,
+// metrics and logging promote the same keys.
+package baggageattr
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// Attributes returns an attribute for every key in keys that is present in the baggage of ctx.
+func Attributes(ctx context.Context, keys []string) []attribute.KeyValue {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	bag := baggage.FromContext(ctx)
+	if bag.Len() == 0 {
+		return nil
+	}
+
+	var attrs []attribute.KeyValue
+	for _, key := range keys {
+		if member := bag.Member(key); member.Key() != "" {
+			attrs = append(attrs, attribute.String(key, member.Value()))
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,27 @@
+package baggageattr
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func TestAttributes(t *testing.T) {
+	tenant, _ := baggage.NewMember("tenant.id", "acme")
+	user, _ := baggage.NewMember("user.id", "42")
+	bag, _ := baggage.New(tenant, user)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	got := Attributes(ctx, []string{"tenant.id", "region"})
+	if len(got) != 1 || got[0] != attribute.String("tenant.id", "acme") {
+		t.Errorf("Attributes = %v, want only tenant.id=acme", got)
+	}
+	if got := Attributes(ctx, nil); got != nil {
+		t.Errorf("Attributes without keys = %v, want nil", got)
+	}
+	if got := Attributes(context.Background(), []string{"tenant.id"}); got != nil {
+		t.Errorf("Attributes without baggage = %v, want nil", got)
+	}
+}
@@ -0,0 +1,268 @@
+// Package cpuprofile summarises the CPU profiles written by runtime/pprof, so that the functions
+// a span spent its CPU time in can be attached to it without a separate pprof tool.
+package cpuprofile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Function is a function and the number of samples taken while it was running.
+type Function struct {
+	Name    string
+	Samples int64
+}
+
+// Summary is the outcome of Summarize.
+type Summary struct {
+	// Samples is the number of samples that matched the label filter.
+	Samples int64
+	// Top lists the functions with the most samples, most first.
+	Top []Function
+}
+
+// Field numbers of the profile.proto messages read by Summarize.
+const (
+	profileSample      = 2
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+
+	sampleLocationID = 1
+	sampleValue      = 2
+	sampleLabel      = 3
+
+	labelKey = 1
+	labelStr = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+
+	functionID   = 1
+	functionName = 2
+)
+
+type sample struct {
+	locations []uint64
+	count     int64
+	labels    map[uint64]uint64
+}
+
+// Summarize parses a gzipped pprof profile and returns the top functions by flat samples, the
+// samples taken while the function itself, rather than one of its callees, was running. When key
+// is not empty only samples carrying the pprof label key=value are counted.
+func Summarize(data []byte, key, value string, top int) (Summary, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return Summary{}, err
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	var (
+		samples   []sample
+		strs      []string
+		leafFuncs = map[uint64]uint64{} // location ID to the function ID of its innermost line
+		funcNames = map[uint64]uint64{} // function ID to its name's string table index
+	)
+	err = fields(raw, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch num {
+		case profileSample:
+			s, err := parseSample(v)
+			if err != nil {
+				return err
+			}
+			samples = append(samples, s)
+		case profileLocation:
+			id, fn, err := parseLocation(v)
+			if err != nil {
+				return err
+			}
+			leafFuncs[id] = fn
+		case profileFunction:
+			var id, name uint64
+			err := fields(v, func(num protowire.Number, _ protowire.Type, _ []byte, x uint64) error {
+				switch num {
+				case functionID:
+					id = x
+				case functionName:
+					name = x
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			funcNames[id] = name
+		case profileStringTable:
+			strs = append(strs, string(v))
+		}
+		return nil
+	})
+	if err != nil {
+		return Summary{}, err
+	}
+
+	str := func(i uint64) string {
+		if i < uint64(len(strs)) {
+			return strs[i]
+		}
+		return ""
+	}
+
+	var summary Summary
+	flat := map[string]int64{}
+	for _, s := range samples {
+		if key != "" && !hasLabel(s, str, key, value) {
+			continue
+		}
+		summary.Samples += s.count
+		if len(s.locations) == 0 {
+			continue
+		}
+		if name := str(funcNames[leafFuncs[s.locations[0]]]); name != "" {
+			flat[name] += s.count
+		}
+	}
+
+	for name, n := range flat {
+		summary.Top = append(summary.Top, Function{Name: name, Samples: n})
+	}
+	sort.Slice(summary.Top, func(i, j int) bool {
+		if summary.Top[i].Samples != summary.Top[j].Samples {
+			return summary.Top[i].Samples > summary.Top[j].Samples
+		}
+		return summary.Top[i].Name < summary.Top[j].Name
+	})
+	if len(summary.Top) > top {
+		summary.Top = summary.Top[:top]
+	}
+	return summary, nil
+}
+
+func hasLabel(s sample, str func(uint64) string, key, value string) bool {
+	for k, v := range s.labels {
+		if str(k) == key && str(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSample reads the locations, the sample count, which is the first value of a CPU
+// profile sample, and the string labels of a Sample message.
+func parseSample(b []byte) (sample, error) {
+	s := sample{labels: map[uint64]uint64{}}
+	var values []uint64
+	err := fields(b, func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error {
+		var err error
+		switch num {
+		case sampleLocationID:
+			s.locations, err = varints(s.locations, typ, v, x)
+		case sampleValue:
+			values, err = varints(values, typ, v, x)
+		case sampleLabel:
+			var k, str uint64
+			err = fields(v, func(num protowire.Number, _ protowire.Type, _ []byte, x uint64) error {
+				switch num {
+				case labelKey:
+					k = x
+				case labelStr:
+					str = x
+				}
+				return nil
+			})
+			if str != 0 {
+				s.labels[k] = str
+			}
+		}
+		return err
+	})
+	if len(values) > 0 {
+		s.count = int64(values[0])
+	}
+	return s, err
+}
+
+// parseLocation returns the ID of a Location message and the function of its first line, which
+// is the innermost one when functions were inlined.
+func parseLocation(b []byte) (id, function uint64, err error) {
+	first := true
+	err = fields(b, func(num protowire.Number, _ protowire.Type, v []byte, x uint64) error {
+		switch num {
+		case locationID:
+			id = x
+		case locationLine:
+			if !first {
+				return nil
+			}
+			first = false
+			return fields(v, func(num protowire.Number, _ protowire.Type, _ []byte, x uint64) error {
+				if num == lineFunctionID {
+					function = x
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return id, function, err
+}
+
+// fields calls fn for every field of the protobuf message b, with the field's bytes for
+// length-delimited fields and its value for varint fields.
+func fields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, x uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var (
+			v []byte
+			x uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varints appends the values of a repeated integer field, which may or may not be packed.
+func varints(dst []uint64, typ protowire.Type, v []byte, x uint64) ([]uint64, error) {
+	if typ == protowire.VarintType {
+		return append(dst, x), nil
+	}
+	for len(v) > 0 {
+		x, n := protowire.ConsumeVarint(v)
+		if n < 0 {
+			return dst, protowire.ParseError(n)
+		}
+		dst = append(dst, x)
+		v = v[n:]
+	}
+	return dst, nil
+}
@@ -0,0 +1,147 @@
+package cpuprofile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func message(fields ...func([]byte) []byte) []byte {
+	var b []byte
+	for _, f := range fields {
+		b = f(b)
+	}
+	return b
+}
+
+func varint(num protowire.Number, v uint64) func([]byte) []byte {
+	return func(b []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+}
+
+func packed(num protowire.Number, vs ...uint64) func([]byte) []byte {
+	return func(b []byte) []byte {
+		var p []byte
+		for _, v := range vs {
+			p = protowire.AppendVarint(p, v)
+		}
+		return bytesField(num, p)(b)
+	}
+}
+
+func bytesField(num protowire.Number, v []byte) func([]byte) []byte {
+	return func(b []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, v)
+	}
+}
+
+// testProfile returns a gzipped profile with functions main.a and main.b, where main.b is
+// inlined into main.a at location 2, and samples labeled span.id=abc and span.id=def.
+func testProfile(t *testing.T) []byte {
+	t.Helper()
+	strs := []string{"", "samples", "count", "main.a", "main.b", "span.id", "abc", "def"}
+	fields := []func([]byte) []byte{
+		bytesField(profileSample, message(
+			packed(sampleLocationID, 1, 2), packed(sampleValue, 3, 30),
+			bytesField(sampleLabel, message(varint(labelKey, 5), varint(labelStr, 6))),
+		)),
+		bytesField(profileSample, message(
+			packed(sampleLocationID, 2), packed(sampleValue, 7, 70),
+			bytesField(sampleLabel, message(varint(labelKey, 5), varint(labelStr, 7))),
+		)),
+		// Unpacked repeated fields are valid too.
+		bytesField(profileSample, message(varint(sampleLocationID, 1), varint(sampleValue, 2), varint(sampleValue, 20))),
+		bytesField(profileLocation, message(varint(locationID, 1), bytesField(locationLine, message(varint(lineFunctionID, 1))))),
+		bytesField(profileLocation, message(
+			varint(locationID, 2),
+			bytesField(locationLine, message(varint(lineFunctionID, 2))),
+			bytesField(locationLine, message(varint(lineFunctionID, 1))),
+		)),
+		bytesField(profileFunction, message(varint(functionID, 1), varint(functionName, 3))),
+		bytesField(profileFunction, message(varint(functionID, 2), varint(functionName, 4))),
+	}
+	for _, s := range strs {
+		fields = append(fields, bytesField(profileStringTable, []byte(s)))
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(message(fields...)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSummarize(t *testing.T) {
+	data := testProfile(t)
+
+	tests := []struct {
+		name       string
+		key, value string
+		top        int
+		want       Summary
+	}{
+		{
+			name: "all samples",
+			top:  5,
+			want: Summary{Samples: 12, Top: []Function{{"main.b", 7}, {"main.a", 5}}},
+		},
+		{
+			name: "top limit",
+			top:  1,
+			want: Summary{Samples: 12, Top: []Function{{"main.b", 7}}},
+		},
+		{
+			name: "label filter",
+			key:  "span.id", value: "abc",
+			top:  5,
+			want: Summary{Samples: 3, Top: []Function{{"main.a", 3}}},
+		},
+		{
+			name: "inlined leaf",
+			key:  "span.id", value: "def",
+			top:  5,
+			want: Summary{Samples: 7, Top: []Function{{"main.b", 7}}},
+		},
+		{
+			name: "no matching label",
+			key:  "span.id", value: "missing",
+			top:  5,
+			want: Summary{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Summarize(data, tt.key, tt.value, tt.top)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeRejectsInvalidProfiles(t *testing.T) {
+	if _, err := Summarize([]byte("not gzip"), "", "", 5); err == nil {
+		t.Error("Summarize accepted data that is not gzipped")
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte{0x12, 0x05, 0x01})
+	zw.Close()
+	if _, err := Summarize(buf.Bytes(), "", "", 5); err == nil {
+		t.Error("Summarize accepted a truncated message")
+	}
+}
@@ -0,0 +1,28 @@
+// Package goroutine identifies goroutines so that per-goroutine state, such as pprof labels or
+// slow-operation stacks, can be tied to the goroutine that set it up.
+package goroutine
+
+import "runtime"
+
+// ID returns the ID of the calling goroutine, parsed from its stack header
+// "goroutine 42 [running]:", or 0 if the header cannot be parsed.
+func ID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+
+	const prefix = "goroutine "
+	if len(header) <= len(prefix) || string(header[:len(prefix)]) != prefix {
+		return 0
+	}
+	var id uint64
+	for _, c := range header[len(prefix):] {
+		if c == ' ' {
+			return id
+		}
+		if c < '0' || c > '9' {
+			return 0
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return 0
+}
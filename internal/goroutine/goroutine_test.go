@@ -0,0 +1,19 @@
+package goroutine
+
+import "testing"
+
+func TestIDIdentifiesTheCallingGoroutine(t *testing.T) {
+	id := ID()
+	if id == 0 {
+		t.Fatal("ID() = 0 on a running goroutine")
+	}
+	if again := ID(); again != id {
+		t.Errorf("ID() = %d then %d on the same goroutine", id, again)
+	}
+
+	other := make(chan uint64)
+	go func() { other <- ID() }()
+	if got := <-other; got == 0 || got == id {
+		t.Errorf("ID() on another goroutine = %d, want a distinct non-zero ID (this one is %d)", got, id)
+	}
+}
@@ -2,10 +2,19 @@ package apw_logging
 
 import (
 	"context"
+	"fmt"
 
+	"otel-library/internal/baggageattr"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
+// logEventName is the name of span events mirrored from log entries.
+const logEventName = "log"
+
 // OtelLogging defines methods for logging operations.
 type OtelLogging interface {
 	Debug(args ...interface{})
@@ -27,17 +36,66 @@ type OtelLogging interface {
 }
 
 type otelLog struct {
-	logger *zap.SugaredLogger
+	logger      *zap.SugaredLogger
+	baggageKeys []string
+	spanEvents  bool
+	// span and fields are set by WithContext: span receives mirrored log events and
+	// fields are the context fields added to the logger.
+	span   trace.Span
+	fields []attribute.KeyValue
+}
+
+// Option configures optional behaviour of the OtelLogging returned by NewOtelLogging.
+type Option func(*otelLog)
+
+// WithBaggageKeys adds the listed baggage keys, when present in the context, as fields of the
+// logger returned by WithContext.
+func WithBaggageKeys(keys ...string) Option {
+	return func(l *otelLog) {
+		l.baggageKeys = append(l.baggageKeys, keys...)
+	}
+}
+
+// PromoteBaggage returns a copy of l that also adds the listed baggage keys as fields of the
+// logger returned by WithContext, as WithBaggageKeys does. Loggers not created by this package
+// are returned unchanged.
+func PromoteBaggage(l OtelLogging, keys ...string) OtelLogging {
+	ol, ok := l.(*otelLog)
+	if !ok || len(keys) == 0 {
+		return l
+	}
+	promoted := *ol
+	promoted.baggageKeys = append(ol.baggageKeys[:len(ol.baggageKeys):len(ol.baggageKeys)], keys...)
+	return &promoted
+}
+
+// WithSpanEvents mirrors Warn and Error entries of a logger returned by WithContext as "log"
+// events on the active span, with the message, level and context fields as attributes.
+func WithSpanEvents() Option {
+	return func(l *otelLog) {
+		l.spanEvents = true
+	}
 }
 
 // NewOtelLogging creates a new instance of OtelLogging.
-func NewOtelLogging() OtelLogging {
+func NewOtelLogging(opts ...Option) OtelLogging {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync() // flushes buffer, if any
 	sugar := logger.Sugar()
-	return &otelLog{
+	l := &otelLog{
 		logger: sugar,
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// NewNoopLogging creates an OtelLogging that discards every log entry.
+func NewNoopLogging() OtelLogging {
+	return &otelLog{
+		logger: zap.NewNop().Sugar(),
+	}
 }
 
 func (l *otelLog) Debug(args ...interface{}) {
@@ -58,18 +116,22 @@ func (l *otelLog) Infof(template string, args ...interface{}) {
 
 func (l *otelLog) Warn(args ...interface{}) {
 	l.logger.Warn(args...)
+	l.addSpanEvent(zapcore.WarnLevel, func() string { return fmt.Sprint(args...) })
 }
 
 func (l *otelLog) Warnf(template string, args ...interface{}) {
 	l.logger.Warnf(template, args...)
+	l.addSpanEvent(zapcore.WarnLevel, func() string { return fmt.Sprintf(template, args...) })
 }
 
 func (l *otelLog) Error(args ...interface{}) {
 	l.logger.Error(args...)
+	l.addSpanEvent(zapcore.ErrorLevel, func() string { return fmt.Sprint(args...) })
 }
 
 func (l *otelLog) Errorf(template string, args ...interface{}) {
 	l.logger.Errorf(template, args...)
+	l.addSpanEvent(zapcore.ErrorLevel, func() string { return fmt.Sprintf(template, args...) })
 }
 
 func (l *otelLog) DPanic(args ...interface{}) {
@@ -100,8 +162,49 @@ func (l *otelLog) Logf(template string, args ...interface{}) {
 	l.logger.Infof(template, args...)
 }
 
+// WithContext returns a logger whose entries carry the trace_id, span_id and trace_flags of the
+// span in ctx, plus any configured baggage keys. With WithSpanEvents, Warn and Error entries are
+// also added to that span as events.
 func (l *otelLog) WithContext(ctx context.Context) OtelLogging {
-	return &otelLog{
-		logger: l.logger.With(ctx),
+	child := *l
+	child.fields = l.fields[:len(l.fields):len(l.fields)]
+
+	var zapFields []interface{}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		zapFields = append(zapFields,
+			"trace_id", sc.TraceID().String(),
+			"span_id", sc.SpanID().String(),
+			"trace_flags", sc.TraceFlags().String(),
+		)
+	}
+	for _, attr := range baggageattr.Attributes(ctx, l.baggageKeys) {
+		zapFields = append(zapFields, string(attr.Key), attr.Value.AsString())
+		child.fields = append(child.fields, attr)
+	}
+	if len(zapFields) > 0 {
+		child.logger = l.logger.With(zapFields...)
 	}
+
+	if l.spanEvents {
+		if span := trace.SpanFromContext(ctx); span.IsRecording() {
+			child.span = span
+		}
+	}
+	return &child
+}
+
+// addSpanEvent mirrors a log entry onto the span captured by WithContext. The message is only
+// formatted when there is a recording span and the level is enabled.
+func (l *otelLog) addSpanEvent(level zapcore.Level, message func() string) {
+	if l.span == nil || !l.span.IsRecording() || !l.logger.Desugar().Core().Enabled(level) {
+		return
+	}
+
+	attrs := make([]attribute.KeyValue, 0, len(l.fields)+2)
+	attrs = append(attrs,
+		attribute.String("log.message", message()),
+		attribute.String("log.severity", level.CapitalString()),
+	)
+	attrs = append(attrs, l.fields...)
+	l.span.AddEvent(logEventName, trace.WithAttributes(attrs...))
 }
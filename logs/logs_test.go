@@ -0,0 +1,136 @@
+package apw_logging
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// newObservedLogging returns an OtelLogging writing entries at level and above to the returned
+// observer.
+func newObservedLogging(level zapcore.Level, opts ...Option) (OtelLogging, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	l := &otelLog{logger: zap.New(core).Sugar()}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, logs
+}
+
+func TestWithContextAddsBaggageKeys(t *testing.T) {
+	l, logs := newObservedLogging(zapcore.InfoLevel, WithBaggageKeys("tenant.id"))
+	tenant, _ := baggage.NewMember("tenant.id", "acme")
+	user, _ := baggage.NewMember("user.id", "42")
+	bag, _ := baggage.New(tenant, user)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	l.WithContext(ctx).Info("hello")
+	l.Info("plain")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["tenant.id"] != "acme" {
+		t.Errorf("fields = %v, want tenant.id=acme", fields)
+	}
+	if _, ok := fields["user.id"]; ok {
+		t.Errorf("fields = %v include user.id, which is not promoted", fields)
+	}
+	if len(entries[1].Context) != 0 {
+		t.Errorf("parent logger gained fields %v", entries[1].ContextMap())
+	}
+}
+
+func TestWithContextAddsTraceIDs(t *testing.T) {
+	l, logs := newObservedLogging(zapcore.InfoLevel)
+	tp := sdktrace.NewTracerProvider()
+	ctx, span := tp.Tracer("test").Start(context.Background(), "work")
+	defer span.End()
+
+	l.WithContext(ctx).Info("hello")
+	l.WithContext(context.Background()).Info("no span")
+
+	entries := logs.AllUntimed()
+	sc := span.SpanContext()
+	fields := entries[0].ContextMap()
+	if fields["trace_id"] != sc.TraceID().String() || fields["span_id"] != sc.SpanID().String() || fields["trace_flags"] != sc.TraceFlags().String() {
+		t.Errorf("fields = %v, want the IDs of the span", fields)
+	}
+	if len(entries[1].Context) != 0 {
+		t.Errorf("entry without a span has fields %v", entries[1].ContextMap())
+	}
+}
+
+func TestWithSpanEventsMirrorsWarningsAndErrors(t *testing.T) {
+	l, _ := newObservedLogging(zapcore.InfoLevel, WithSpanEvents(), WithBaggageKeys("tenant.id"))
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tenant, _ := baggage.NewMember("tenant.id", "acme")
+	bag, _ := baggage.New(tenant)
+	ctx, span := tp.Tracer("test").Start(baggage.ContextWithBaggage(context.Background(), bag), "work")
+
+	logger := l.WithContext(ctx)
+	logger.Info("not mirrored")
+	logger.Warnf("retry %d", 2)
+	logger.Error("failed")
+	span.End()
+
+	events := recorder.Ended()[0].Events()
+	if len(events) != 2 {
+		t.Fatalf("got %d span events, want one per warning and error", len(events))
+	}
+	want := []attribute.KeyValue{
+		attribute.String("log.message", "retry 2"),
+		attribute.String("log.severity", "WARN"),
+		attribute.String("tenant.id", "acme"),
+	}
+	if events[0].Name != logEventName || !reflect.DeepEqual(events[0].Attributes, want) {
+		t.Errorf("event = %s %v, want %s %v", events[0].Name, events[0].Attributes, logEventName, want)
+	}
+	if got := events[1].Attributes[1]; got != attribute.String("log.severity", "ERROR") {
+		t.Errorf("second event severity = %v, want ERROR", got)
+	}
+}
+
+func TestWithSpanEventsSkipsDisabledLevels(t *testing.T) {
+	l, _ := newObservedLogging(zapcore.ErrorLevel, WithSpanEvents())
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "work")
+
+	l.WithContext(ctx).Warn("filtered out")
+	span.End()
+
+	if events := recorder.Ended()[0].Events(); len(events) != 0 {
+		t.Errorf("got events %v for a disabled level", events)
+	}
+}
+
+func TestPromoteBaggageAddsKeysToCopy(t *testing.T) {
+	l, logs := newObservedLogging(zapcore.InfoLevel, WithBaggageKeys("user.id"))
+	tenant, _ := baggage.NewMember("tenant.id", "acme")
+	user, _ := baggage.NewMember("user.id", "42")
+	bag, _ := baggage.New(tenant, user)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	PromoteBaggage(l, "tenant.id").WithContext(ctx).Info("promoted")
+	l.WithContext(ctx).Info("original")
+
+	entries := logs.AllUntimed()
+	if fields := entries[0].ContextMap(); fields["tenant.id"] != "acme" || fields["user.id"] != "42" {
+		t.Errorf("promoted logger fields = %v, want tenant.id and user.id", fields)
+	}
+	if fields := entries[1].ContextMap(); fields["tenant.id"] != nil {
+		t.Errorf("original logger fields = %v, want it unchanged", fields)
+	}
+}
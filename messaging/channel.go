@@ -0,0 +1,76 @@
+package apw_messaging
+
+import (
+	"context"
+	"sync"
+)
+
+// ChannelBroker is an in-memory broker backed by buffered channels, one per destination. It is
+// a reference implementation of Publisher and Receiver for tests and examples.
+type ChannelBroker struct {
+	mu     sync.Mutex
+	buffer int
+	queues map[string]chan *Message
+}
+
+// NewChannelBroker returns a ChannelBroker whose destinations buffer up to buffer messages.
+func NewChannelBroker(buffer int) *ChannelBroker {
+	return &ChannelBroker{buffer: buffer, queues: make(map[string]chan *Message)}
+}
+
+func (b *ChannelBroker) queue(destination string) chan *Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.queues[destination]
+	if !ok {
+		q = make(chan *Message, b.buffer)
+		b.queues[destination] = q
+	}
+	return q
+}
+
+// Publish enqueues a copy of msg on its destination, blocking while the buffer is full.
+func (b *ChannelBroker) Publish(ctx context.Context, msg *Message) error {
+	sent := *msg
+	sent.Headers = append(sent.Headers[:0:0], msg.Headers...)
+	select {
+	case b.queue(msg.Destination) <- &sent:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Receiver returns a Receiver reading from destination.
+func (b *ChannelBroker) Receiver(destination string) Receiver {
+	return &channelReceiver{destination: destination, queue: b.queue(destination)}
+}
+
+type channelReceiver struct {
+	destination string
+	queue       chan *Message
+}
+
+func (r *channelReceiver) Destination() string {
+	return r.destination
+}
+
+// Receive waits for one message, then returns it with any others already queued, up to max.
+func (r *channelReceiver) Receive(ctx context.Context, max int) ([]*Message, error) {
+	var msgs []*Message
+	select {
+	case msg := <-r.queue:
+		msgs = append(msgs, msg)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	for len(msgs) < max {
+		select {
+		case msg := <-r.queue:
+			msgs = append(msgs, msg)
+		default:
+			return msgs, nil
+		}
+	}
+	return msgs, nil
+}
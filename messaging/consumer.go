@@ -0,0 +1,140 @@
+package apw_messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartConsumerSpan starts a process span for msg, parented to the producer context extracted
+// from msg.Headers and linked to the span in ctx, such as a receive span. It also records
+// messaging.process.lag when msg.PublishTime is set. End the span with EndSpan.
+func (i *Instrumentation) StartConsumerSpan(ctx context.Context, msg *Message) (context.Context, trace.Span) {
+	attrs := i.attributes(semconv.MessagingOperationTypeDeliver, msg.Destination)
+	i.recordLag(ctx, msg, attrs)
+
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(append(attrs, messageAttributes(msg)...)...),
+	}
+	if local := trace.SpanContextFromContext(ctx); local.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: local}))
+	}
+	ctx = i.tracing.Extract(ctx, &msg.Headers)
+	return i.tracing.StartSpan(ctx, spanName(msg.Destination, "process"), opts...)
+}
+
+// StartBatchSpan starts a single process span for msgs, linked to the producer context of every
+// message. End the span with EndSpan.
+func (i *Instrumentation) StartBatchSpan(ctx context.Context, destination string, msgs []*Message) (context.Context, trace.Span) {
+	attrs := i.attributes(semconv.MessagingOperationTypeDeliver, destination)
+	for _, msg := range msgs {
+		i.recordLag(ctx, msg, attrs)
+	}
+
+	return i.tracing.StartSpanWithLinks(ctx, spanName(destination, "process"), i.producerLinks(msgs),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(append(attrs, semconv.MessagingBatchMessageCount(len(msgs)))...),
+	)
+}
+
+// Receive reads up to max messages from r inside a receive span linked to every received
+// message, and records messaging.receive.duration.
+func (i *Instrumentation) Receive(ctx context.Context, r Receiver, max int) ([]*Message, error) {
+	_, msgs, err := i.receive(ctx, r, max)
+	return msgs, err
+}
+
+// receive implements Receive and also returns the context of the receive span, so that Consume
+// can link process spans to it.
+func (i *Instrumentation) receive(ctx context.Context, r Receiver, max int) (context.Context, []*Message, error) {
+	attrs := i.attributes(semconv.MessagingOperationTypeReceive, r.Destination())
+
+	start := time.Now()
+	ctx, span := i.tracing.StartSpan(ctx, spanName(r.Destination(), "receive"),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+	msgs, err := r.Receive(ctx, max)
+	if err == nil {
+		span.SetAttributes(semconv.MessagingBatchMessageCount(len(msgs)))
+		for _, sc := range i.producerLinks(msgs) {
+			i.tracing.AddLink(span, sc)
+		}
+	}
+	i.EndSpan(ctx, span, err)
+	recordDuration(ctx, i.receiveDuration, start, attrs, err)
+	return ctx, msgs, err
+}
+
+// Process runs h for msg inside a process span and records messaging.process.duration.
+func (i *Instrumentation) Process(ctx context.Context, msg *Message, h Handler) error {
+	start := time.Now()
+	ctx, span := i.StartConsumerSpan(ctx, msg)
+	err := h(ctx, msg)
+	i.EndSpan(ctx, span, err)
+	recordDuration(ctx, i.processDuration, start, i.attributes(semconv.MessagingOperationTypeDeliver, msg.Destination), err)
+	return err
+}
+
+// ProcessBatch runs h for msgs inside one process span and records messaging.process.duration.
+func (i *Instrumentation) ProcessBatch(ctx context.Context, destination string, msgs []*Message, h BatchHandler) error {
+	start := time.Now()
+	ctx, span := i.StartBatchSpan(ctx, destination, msgs)
+	err := h(ctx, msgs)
+	i.EndSpan(ctx, span, err)
+	recordDuration(ctx, i.processDuration, start, i.attributes(semconv.MessagingOperationTypeDeliver, destination), err)
+	return err
+}
+
+// Consume receives one batch of up to max messages from r and runs h for each message. In
+// PerMessage mode every message gets its own process span; in PerBatch mode the whole batch
+// shares one. Per-message process spans are linked to the receive span. Errors returned by h
+// are joined; every message is handled regardless.
+func (i *Instrumentation) Consume(ctx context.Context, r Receiver, max int, h Handler) error {
+	receiveCtx, msgs, err := i.receive(ctx, r, max)
+	if err != nil || len(msgs) == 0 {
+		return err
+	}
+
+	if i.mode == PerBatch {
+		return i.ProcessBatch(ctx, r.Destination(), msgs, func(ctx context.Context, msgs []*Message) error {
+			var errs []error
+			for _, msg := range msgs {
+				errs = append(errs, h(ctx, msg))
+			}
+			return errors.Join(errs...)
+		})
+	}
+
+	var errs []error
+	for _, msg := range msgs {
+		errs = append(errs, i.Process(receiveCtx, msg, h))
+	}
+	return errors.Join(errs...)
+}
+
+// producerLinks returns the valid producer span contexts carried by msgs.
+func (i *Instrumentation) producerLinks(msgs []*Message) []trace.SpanContext {
+	links := make([]trace.SpanContext, 0, len(msgs))
+	for _, msg := range msgs {
+		sc := trace.SpanContextFromContext(i.tracing.Extract(context.Background(), &msg.Headers))
+		if sc.IsValid() {
+			links = append(links, sc)
+		}
+	}
+	return links
+}
+
+// recordLag records the time between msg being published and now.
+func (i *Instrumentation) recordLag(ctx context.Context, msg *Message, attrs []attribute.KeyValue) {
+	if msg.PublishTime.IsZero() {
+		return
+	}
+	i.processLag.Record(ctx, time.Since(msg.PublishTime).Milliseconds(), metric.WithAttributes(attrs...))
+}
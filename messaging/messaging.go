@@ -0,0 +1,152 @@
+// Package apw_messaging instruments message producers and consumers independently of the broker.
+// Broker clients plug in through the Publisher and Receiver adapters; ChannelBroker is an
+// in-memory reference implementation.
+package apw_messaging
+
+import (
+	"context"
+	"time"
+
+	apw_metrics "otel-library/metrics"
+	"otel-library/otelBuilder"
+	apw_tracing "otel-library/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// scopeName is the instrumentation scope of the spans and metrics recorded by this package.
+const scopeName = "otel-library/messaging"
+
+// Message is a broker-agnostic message. Adapters convert their client's message type to and
+// from Message, copying headers so that trace context survives the trip through the broker.
+type Message struct {
+	ID          string
+	Destination string
+	Key         string
+	Body        []byte
+	Headers     []apw_tracing.MessageHeader
+	// PublishTime is when the message was published. Publish sets it when empty; adapters
+	// should fill it from the broker timestamp on receive so that process lag can be measured.
+	PublishTime time.Time
+}
+
+// Publisher is implemented by adapters that send messages to a broker.
+type Publisher interface {
+	Publish(ctx context.Context, msg *Message) error
+}
+
+// Receiver is implemented by adapters that read messages from one destination.
+type Receiver interface {
+	Destination() string
+	// Receive returns up to max messages, blocking until at least one is available or ctx
+	// is done.
+	Receive(ctx context.Context, max int) ([]*Message, error)
+}
+
+// Handler processes a single message.
+type Handler func(ctx context.Context, msg *Message) error
+
+// BatchHandler processes a batch of messages at once.
+type BatchHandler func(ctx context.Context, msgs []*Message) error
+
+// SpanMode selects how Consume creates process spans.
+type SpanMode int
+
+const (
+	// PerMessage creates one process span per message, parented to the producer's context.
+	PerMessage SpanMode = iota
+	// PerBatch creates one process span per received batch, linked to every message's
+	// producer context.
+	PerBatch
+)
+
+// Option configures an Instrumentation.
+type Option func(*Instrumentation)
+
+// WithSpanMode sets the span mode used by Consume. The default is PerMessage.
+func WithSpanMode(mode SpanMode) Option {
+	return func(i *Instrumentation) {
+		i.mode = mode
+	}
+}
+
+// WithAttributes adds attributes, for example the consumer group, to every span and metric.
+func WithAttributes(attrs ...attribute.KeyValue) Option {
+	return func(i *Instrumentation) {
+		i.attrs = append(i.attrs, attrs...)
+	}
+}
+
+// Instrumentation creates producer and consumer spans and records messaging metrics for one
+// messaging system.
+type Instrumentation struct {
+	tracing apw_tracing.OtelTracing
+	system  string
+	mode    SpanMode
+	attrs   []attribute.KeyValue
+
+	publishDuration metric.Int64Histogram
+	receiveDuration metric.Int64Histogram
+	processDuration metric.Int64Histogram
+	processLag      metric.Int64Histogram
+}
+
+// New returns an Instrumentation for the messaging system named system, for example "kafka",
+// "nats" or "aws_sqs".
+func New(o *otelBuilder.Otel, system string, opts ...Option) (*Instrumentation, error) {
+	i := &Instrumentation{
+		tracing: o.Tracer(scopeName, ""),
+		system:  system,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+
+	m := o.Meter(scopeName, "")
+	var err error
+	if i.publishDuration, err = createHistogram(m, "messaging.publish.duration", "Duration of publish operations"); err != nil {
+		return nil, err
+	}
+	if i.receiveDuration, err = createHistogram(m, "messaging.receive.duration", "Duration of receive operations"); err != nil {
+		return nil, err
+	}
+	if i.processDuration, err = createHistogram(m, "messaging.process.duration", "Duration of message processing"); err != nil {
+		return nil, err
+	}
+	if i.processLag, err = createHistogram(m, "messaging.process.lag", "Time between publishing a message and starting to process it"); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func createHistogram(m apw_metrics.OtelMetric, name, description string) (metric.Int64Histogram, error) {
+	return m.CreateHistogram(name, metric.WithDescription(description), metric.WithUnit("ms"))
+}
+
+// attributes returns the common span and metric attributes for an operation on destination.
+func (i *Instrumentation) attributes(operation attribute.KeyValue, destination string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(i.attrs)+3)
+	attrs = append(attrs, semconv.MessagingSystemKey.String(i.system), operation)
+	if destination != "" {
+		attrs = append(attrs, semconv.MessagingDestinationName(destination))
+	}
+	return append(attrs, i.attrs...)
+}
+
+// recordDuration records the time since start on h, with error.type set when err is not nil.
+func recordDuration(ctx context.Context, h metric.Int64Histogram, start time.Time, attrs []attribute.KeyValue, err error) {
+	if err != nil {
+		attrs = append(attrs[:len(attrs):len(attrs)], semconv.ErrorTypeKey.String(errorType(err)))
+	}
+	h.Record(ctx, time.Since(start).Milliseconds(), metric.WithAttributes(attrs...))
+}
+
+// spanName follows the messaging convention "<destination> <operation>".
+func spanName(destination, operation string) string {
+	if destination == "" {
+		return operation
+	}
+	return destination + " " + operation
+}
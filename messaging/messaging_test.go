@@ -0,0 +1,246 @@
+package apw_messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	apw_logging "otel-library/logs"
+	apw_metrics "otel-library/metrics"
+	"otel-library/otelBuilder"
+	apw_tracing "otel-library/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type testTelemetry struct {
+	otel   *otelBuilder.Otel
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+func newTestTelemetry() *testTelemetry {
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	l := apw_logging.NewNoopLogging()
+
+	tracing := apw_tracing.NewTracing(tp.Tracer("test"), l, apw_tracing.WithTracerProvider(tp))
+	metrics := apw_metrics.NewMetric(mp.Meter("test"), apw_metrics.WithMeterProvider(mp))
+	return &testTelemetry{otel: otelBuilder.NewOtel(tracing, metrics, l), spans: spans, reader: reader}
+}
+
+// spansNamed returns the ended spans called name.
+func (tt *testTelemetry) spansNamed(name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range tt.spans.Ended() {
+		if span.Name() == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// histogram returns the data points recorded for the histogram called name.
+func (tt *testTelemetry) histogram(t *testing.T, name string) []metricdata.HistogramDataPoint[int64] {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := tt.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data.(metricdata.Histogram[int64]).DataPoints
+			}
+		}
+	}
+	return nil
+}
+
+func newInstrumentation(t *testing.T, tt *testTelemetry, opts ...Option) *Instrumentation {
+	t.Helper()
+	inst, err := New(tt.otel, "channel", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inst
+}
+
+func TestConsumePerMessageContinuesProducerTrace(t *testing.T) {
+	tt := newTestTelemetry()
+	inst := newInstrumentation(t, tt)
+	broker := NewChannelBroker(4)
+	ctx := context.Background()
+
+	if err := inst.Publish(ctx, broker, &Message{ID: "m1", Destination: "orders", Body: []byte("{}")}); err != nil {
+		t.Fatal(err)
+	}
+	var handled []string
+	err := inst.Consume(ctx, broker.Receiver("orders"), 10, func(ctx context.Context, msg *Message) error {
+		handled = append(handled, msg.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handled) != 1 || handled[0] != "m1" {
+		t.Fatalf("handled %v, want [m1]", handled)
+	}
+
+	publish := tt.spansNamed("orders publish")
+	receive := tt.spansNamed("orders receive")
+	process := tt.spansNamed("orders process")
+	if len(publish) != 1 || len(receive) != 1 || len(process) != 1 {
+		t.Fatalf("got %d publish, %d receive and %d process spans, want one of each", len(publish), len(receive), len(process))
+	}
+	if publish[0].SpanKind() != trace.SpanKindProducer || process[0].SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("span kinds = %v, %v, want producer and consumer", publish[0].SpanKind(), process[0].SpanKind())
+	}
+	if process[0].Parent().SpanID() != publish[0].SpanContext().SpanID() {
+		t.Error("process span is not a child of the publish span")
+	}
+	if links := process[0].Links(); len(links) != 1 || links[0].SpanContext.SpanID() != receive[0].SpanContext().SpanID() {
+		t.Errorf("process span links = %v, want the receive span", links)
+	}
+	if links := receive[0].Links(); len(links) != 1 || links[0].SpanContext.SpanID() != publish[0].SpanContext().SpanID() {
+		t.Errorf("receive span links = %v, want the publish span", links)
+	}
+
+	for _, name := range []string{"messaging.publish.duration", "messaging.receive.duration", "messaging.process.duration", "messaging.process.lag"} {
+		if points := tt.histogram(t, name); len(points) != 1 || points[0].Count != 1 {
+			t.Errorf("%s has points %v, want one measurement", name, points)
+		}
+	}
+}
+
+func TestConsumePerBatchLinksEveryProducer(t *testing.T) {
+	tt := newTestTelemetry()
+	inst := newInstrumentation(t, tt, WithSpanMode(PerBatch))
+	broker := NewChannelBroker(4)
+	ctx := context.Background()
+
+	for _, id := range []string{"m1", "m2"} {
+		if err := inst.Publish(ctx, broker, &Message{ID: id, Destination: "orders"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := inst.Consume(ctx, broker.Receiver("orders"), 10, func(context.Context, *Message) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	process := tt.spansNamed("orders process")
+	if len(process) != 1 {
+		t.Fatalf("got %d process spans, want 1", len(process))
+	}
+	if links := process[0].Links(); len(links) != 2 {
+		t.Errorf("got %d links, want one per producer", len(links))
+	}
+	want := semconv.MessagingBatchMessageCount(2)
+	found := false
+	for _, attr := range process[0].Attributes() {
+		found = found || attr == want
+	}
+	if !found {
+		t.Errorf("process span attributes %v lack %v", process[0].Attributes(), want)
+	}
+}
+
+func TestConsumeRecordsHandlerErrors(t *testing.T) {
+	tt := newTestTelemetry()
+	inst := newInstrumentation(t, tt)
+	broker := NewChannelBroker(4)
+	ctx := context.Background()
+	errHandler := errors.New("handler failed")
+
+	if err := inst.Publish(ctx, broker, &Message{ID: "m1", Destination: "orders"}); err != nil {
+		t.Fatal(err)
+	}
+	err := inst.Consume(ctx, broker.Receiver("orders"), 10, func(context.Context, *Message) error { return errHandler })
+	if !errors.Is(err, errHandler) {
+		t.Fatalf("Consume returned %v, want %v", err, errHandler)
+	}
+
+	process := tt.spansNamed("orders process")
+	if len(process) != 1 || process[0].Status().Code != codes.Error {
+		t.Fatalf("got process spans %v, want one with an error status", process)
+	}
+	points := tt.histogram(t, "messaging.process.duration")
+	if len(points) != 1 {
+		t.Fatalf("got %d process duration points, want 1", len(points))
+	}
+	if v, ok := points[0].Attributes.Value(semconv.ErrorTypeKey); !ok || v.AsString() == "" {
+		t.Errorf("process duration attributes %v lack %s", points[0].Attributes.ToSlice(), semconv.ErrorTypeKey)
+	}
+}
+
+func TestChannelBrokerPublishCopiesMessage(t *testing.T) {
+	broker := NewChannelBroker(1)
+	msg := &Message{Destination: "orders", Headers: []apw_tracing.MessageHeader{{Key: "k", Value: []byte("v")}}}
+	if err := broker.Publish(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	msg.Headers[0].Key = "changed"
+
+	msgs, err := broker.Receiver("orders").Receive(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].Headers[0].Key != "k" {
+		t.Errorf("received %v, want the headers as published", msgs)
+	}
+}
+
+func TestChannelBrokerReceive(t *testing.T) {
+	broker := NewChannelBroker(4)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if err := broker.Publish(ctx, &Message{Destination: "orders"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := broker.Receiver("orders")
+	if msgs, err := r.Receive(ctx, 2); err != nil || len(msgs) != 2 {
+		t.Fatalf("Receive(2) = %d messages, %v; want 2", len(msgs), err)
+	}
+	if msgs, err := r.Receive(ctx, 2); err != nil || len(msgs) != 1 {
+		t.Fatalf("Receive(2) = %d messages, %v; want the remaining 1", len(msgs), err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.Receive(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Receive on an empty queue returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWithAttributesAddsToSpans(t *testing.T) {
+	tt := newTestTelemetry()
+	group := attribute.String("messaging.consumer.group.name", "billing")
+	inst := newInstrumentation(t, tt, WithAttributes(group))
+
+	if err := inst.Publish(context.Background(), NewChannelBroker(1), &Message{Destination: "orders"}); err != nil {
+		t.Fatal(err)
+	}
+	publish := tt.spansNamed("orders publish")
+	if len(publish) != 1 {
+		t.Fatalf("got %d publish spans, want 1", len(publish))
+	}
+	found := false
+	for _, attr := range publish[0].Attributes() {
+		found = found || attr == group
+	}
+	if !found {
+		t.Errorf("publish span attributes %v lack %v", publish[0].Attributes(), group)
+	}
+}
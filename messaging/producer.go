@@ -0,0 +1,64 @@
+package apw_messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartProducerSpan starts a producer span for msg and injects its context into msg.Headers.
+// End the span with EndSpan once the message has been handed to the broker.
+func (i *Instrumentation) StartProducerSpan(ctx context.Context, msg *Message) (context.Context, trace.Span) {
+	attrs := i.attributes(semconv.MessagingOperationTypePublish, msg.Destination)
+	attrs = append(attrs, messageAttributes(msg)...)
+
+	ctx, span := i.tracing.StartSpan(ctx, spanName(msg.Destination, "publish"),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+	i.tracing.Inject(ctx, &msg.Headers)
+	return ctx, span
+}
+
+// EndSpan records err on span, if any, and ends it.
+func (i *Instrumentation) EndSpan(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		i.tracing.RecordError(ctx, span, err)
+	}
+	i.tracing.EndSpan(span)
+}
+
+// Publish sends msg with p inside a producer span and records messaging.publish.duration.
+func (i *Instrumentation) Publish(ctx context.Context, p Publisher, msg *Message) error {
+	if msg.PublishTime.IsZero() {
+		msg.PublishTime = time.Now()
+	}
+
+	start := time.Now()
+	ctx, span := i.StartProducerSpan(ctx, msg)
+	err := p.Publish(ctx, msg)
+	i.EndSpan(ctx, span, err)
+	recordDuration(ctx, i.publishDuration, start, i.attributes(semconv.MessagingOperationTypePublish, msg.Destination), err)
+	return err
+}
+
+// messageAttributes returns the per-message span attributes.
+func messageAttributes(msg *Message) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if msg.ID != "" {
+		attrs = append(attrs, semconv.MessagingMessageID(msg.ID))
+	}
+	if msg.Body != nil {
+		attrs = append(attrs, semconv.MessagingMessageBodySize(len(msg.Body)))
+	}
+	return attrs
+}
+
+// errorType returns the value recorded as error.type on metrics.
+func errorType(err error) string {
+	return fmt.Sprintf("%T", err)
+}
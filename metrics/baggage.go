@@ -0,0 +1,70 @@
+package apw_metrics
+
+import (
+	"context"
+
+	"otel-library/internal/baggageattr"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// WithBaggageKeys adds the listed baggage keys, when present in the measurement context, as
+// attributes on every measurement recorded through instruments created by this OtelMetric.
+func WithBaggageKeys(keys ...string) Option {
+	return func(m *metricImpl) {
+		m.baggageKeys = append(m.baggageKeys, keys...)
+	}
+}
+
+type baggageInt64Counter struct {
+	metric.Int64Counter
+	keys []string
+}
+
+func (c baggageInt64Counter) Add(ctx context.Context, incr int64, opts ...metric.AddOption) {
+	c.Int64Counter.Add(ctx, incr, addOptionsWithBaggage(ctx, c.keys, opts)...)
+}
+
+type baggageInt64UpDownCounter struct {
+	metric.Int64UpDownCounter
+	keys []string
+}
+
+func (c baggageInt64UpDownCounter) Add(ctx context.Context, incr int64, opts ...metric.AddOption) {
+	c.Int64UpDownCounter.Add(ctx, incr, addOptionsWithBaggage(ctx, c.keys, opts)...)
+}
+
+type baggageInt64Histogram struct {
+	metric.Int64Histogram
+	keys []string
+}
+
+func (h baggageInt64Histogram) Record(ctx context.Context, incr int64, opts ...metric.RecordOption) {
+	h.Int64Histogram.Record(ctx, incr, recordOptionsWithBaggage(ctx, h.keys, opts)...)
+}
+
+type baggageInt64Gauge struct {
+	metric.Int64Gauge
+	keys []string
+}
+
+func (g baggageInt64Gauge) Record(ctx context.Context, value int64, opts ...metric.RecordOption) {
+	g.Int64Gauge.Record(ctx, value, recordOptionsWithBaggage(ctx, g.keys, opts)...)
+}
+
+// addOptionsWithBaggage appends the promoted baggage attributes of ctx to a copy of opts, so
+// that the caller's backing array is never written.
+func addOptionsWithBaggage(ctx context.Context, keys []string, opts []metric.AddOption) []metric.AddOption {
+	if attrs := baggageattr.Attributes(ctx, keys); len(attrs) > 0 {
+		return append(opts[:len(opts):len(opts)], metric.WithAttributes(attrs...))
+	}
+	return opts
+}
+
+// recordOptionsWithBaggage appends the promoted baggage attributes of ctx to a copy of opts.
+func recordOptionsWithBaggage(ctx context.Context, keys []string, opts []metric.RecordOption) []metric.RecordOption {
+	if attrs := baggageattr.Attributes(ctx, keys); len(attrs) > 0 {
+		return append(opts[:len(opts):len(opts)], metric.WithAttributes(attrs...))
+	}
+	return opts
+}
@@ -0,0 +1,77 @@
+package apw_metrics
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func tenantContext(tenant string) context.Context {
+	member, _ := baggage.NewMember("tenant.id", tenant)
+	bag, _ := baggage.New(member)
+	return baggage.ContextWithBaggage(context.Background(), bag)
+}
+
+func TestBaggageOptionsDoNotWriteIntoCallerOptions(t *testing.T) {
+	addOpts := make([]metric.AddOption, 1, 4)
+	addOpts[0] = metric.WithAttributes(attribute.String("caller", "value"))
+	recordOpts := make([]metric.RecordOption, 1, 4)
+	recordOpts[0] = metric.WithAttributes(attribute.String("caller", "value"))
+
+	addOptionsWithBaggage(tenantContext("acme"), []string{"tenant.id"}, addOpts)
+	recordOptionsWithBaggage(tenantContext("acme"), []string{"tenant.id"}, recordOpts)
+
+	if spare := addOpts[:cap(addOpts)]; spare[1] != nil {
+		t.Error("addOptionsWithBaggage wrote into the caller's spare capacity")
+	}
+	if spare := recordOpts[:cap(recordOpts)]; spare[1] != nil {
+		t.Error("recordOptionsWithBaggage wrote into the caller's spare capacity")
+	}
+}
+
+func TestBaggageCounterConcurrentSharedOptions(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	m := NewMetric(mp.Meter("test"), WithMeterProvider(mp), WithBaggageKeys("tenant.id"))
+	counter, err := m.CreateCounter("calls")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Options shared by every caller, with spare capacity an append could write into.
+	shared := make([]metric.AddOption, 1, 8)
+	shared[0] = metric.WithAttributes(attribute.String("route", "/orders"))
+
+	var wg sync.WaitGroup
+	for _, tenant := range []string{"acme", "globex"} {
+		wg.Add(1)
+		go func(ctx context.Context) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				counter.Add(ctx, 1, shared...)
+			}
+		}(tenantContext(tenant))
+	}
+	wg.Wait()
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	points := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints
+	if len(points) != 2 {
+		t.Fatalf("got %d data points, want one per tenant", len(points))
+	}
+	for _, point := range points {
+		if point.Value != 100 {
+			tenant, _ := point.Attributes.Value("tenant.id")
+			t.Errorf("tenant %s counted %d, want 100", tenant.AsString(), point.Value)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package apw_metrics
 
 import (
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/metric"
 )
 
@@ -10,30 +11,93 @@ type OtelMetric interface {
 	CreateUpDownCounter(name string, opt ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error)
 	CreateHistogram(name string, opt ...metric.Int64HistogramOption) (metric.Int64Histogram, error)
 	CreateGauge(name string, opt ...metric.Int64GaugeOption) (metric.Int64Gauge, error)
+	CreateObservableCounter(name string, opt ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error)
+	CreateObservableGauge(name string, opt ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error)
+	RegisterCallback(f metric.Callback, instruments ...metric.Observable) (metric.Registration, error)
+	Meter(scopeName, version string) OtelMetric
 }
 
 // metricImpl is a concrete implementation of OtelMetric.
 type metricImpl struct {
-	Meter metric.Meter
+	meter       metric.Meter
+	provider    metric.MeterProvider
+	baggageKeys []string
+}
+
+// Option configures optional behaviour of the OtelMetric returned by NewMetric.
+type Option func(*metricImpl)
+
+// WithMeterProvider sets the provider used to create scoped meters. Without it the global
+// MeterProvider is used.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(m *metricImpl) {
+		m.provider = provider
+	}
 }
 
 // NewMetric creates a new metric instance.
-func NewMetric(meter metric.Meter) OtelMetric {
-	return &metricImpl{Meter: meter}
+func NewMetric(meter metric.Meter, opts ...Option) OtelMetric {
+	m := &metricImpl{meter: meter}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Meter returns an OtelMetric that records through the same provider under the given
+// instrumentation scope name and version.
+func (m *metricImpl) Meter(scopeName, version string) OtelMetric {
+	provider := m.provider
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+
+	scoped := *m
+	scoped.meter = provider.Meter(scopeName, metric.WithInstrumentationVersion(version))
+	return &scoped
 }
 
 func (m *metricImpl) CreateCounter(name string, opt ...metric.Int64CounterOption) (metric.Int64Counter, error) {
-	return m.Meter.Int64Counter(name, opt...)
+	counter, err := m.meter.Int64Counter(name, opt...)
+	if err != nil || len(m.baggageKeys) == 0 {
+		return counter, err
+	}
+	return baggageInt64Counter{Int64Counter: counter, keys: m.baggageKeys}, nil
 }
 
 func (m *metricImpl) CreateUpDownCounter(name string, opt ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
-	return m.Meter.Int64UpDownCounter(name, opt...)
+	counter, err := m.meter.Int64UpDownCounter(name, opt...)
+	if err != nil || len(m.baggageKeys) == 0 {
+		return counter, err
+	}
+	return baggageInt64UpDownCounter{Int64UpDownCounter: counter, keys: m.baggageKeys}, nil
 }
 
 func (m *metricImpl) CreateHistogram(name string, opt ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
-	return m.Meter.Int64Histogram(name, opt...)
+	histogram, err := m.meter.Int64Histogram(name, opt...)
+	if err != nil || len(m.baggageKeys) == 0 {
+		return histogram, err
+	}
+	return baggageInt64Histogram{Int64Histogram: histogram, keys: m.baggageKeys}, nil
 }
 
 func (m *metricImpl) CreateGauge(name string, opt ...metric.Int64GaugeOption) (metric.Int64Gauge, error) {
-	return m.Meter.Int64Gauge(name, opt...)
+	gauge, err := m.meter.Int64Gauge(name, opt...)
+	if err != nil || len(m.baggageKeys) == 0 {
+		return gauge, err
+	}
+	return baggageInt64Gauge{Int64Gauge: gauge, keys: m.baggageKeys}, nil
+}
+
+func (m *metricImpl) CreateObservableCounter(name string, opt ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
+	return m.meter.Int64ObservableCounter(name, opt...)
+}
+
+func (m *metricImpl) CreateObservableGauge(name string, opt ...metric.Int64ObservableGaugeOption) (metric.Int64ObservableGauge, error) {
+	return m.meter.Int64ObservableGauge(name, opt...)
+}
+
+// RegisterCallback registers f to observe the given asynchronous instruments on every collection.
+func (m *metricImpl) RegisterCallback(f metric.Callback, instruments ...metric.Observable) (metric.Registration, error) {
+	return m.meter.RegisterCallback(f, instruments...)
 }
@@ -0,0 +1,100 @@
+package apw_metrics
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	procSelfStat = "/proc/self/stat"
+	procSelfFD   = "/proc/self/fd"
+
+	// clockTicksPerSecond is USER_HZ, which is 100 on every mainstream Linux platform.
+	clockTicksPerSecond = 100
+)
+
+// startProcessMetrics registers process CPU, RSS and open file descriptor instruments backed
+// by /proc. Nothing is registered when /proc is not available.
+func startProcessMetrics(meter metric.Meter) error {
+	if _, err := os.Stat(procSelfStat); err != nil {
+		return nil
+	}
+
+	cpuTime, err := meter.Float64ObservableCounter("process.cpu.time",
+		metric.WithDescription("Total CPU seconds used by the process"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	rss, err := meter.Int64ObservableGauge("process.memory.usage",
+		metric.WithDescription("Resident set size of the process"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	openFDs, err := meter.Int64ObservableGauge("process.open_file_descriptor.count",
+		metric.WithDescription("Number of file descriptors open in the process"),
+		metric.WithUnit("{file_descriptor}"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		if stat, err := readProcStat(); err == nil {
+			o.ObserveFloat64(cpuTime, float64(stat.utime+stat.stime)/clockTicksPerSecond)
+			o.ObserveInt64(rss, stat.rssPages*int64(os.Getpagesize()))
+		}
+		if entries, err := os.ReadDir(procSelfFD); err == nil {
+			// ReadDir holds a descriptor open on /proc/self/fd itself, which is listed too.
+			o.ObserveInt64(openFDs, int64(len(entries)-1))
+		}
+		return nil
+	}, cpuTime, rss, openFDs)
+	return err
+}
+
+type procStat struct {
+	utime    int64
+	stime    int64
+	rssPages int64
+}
+
+// readProcStat reads the fields of /proc/self/stat needed for CPU and memory usage.
+func readProcStat() (procStat, error) {
+	data, err := os.ReadFile(procSelfStat)
+	if err != nil {
+		return procStat{}, err
+	}
+	return parseProcStat(data)
+}
+
+// parseProcStat parses the contents of a /proc/[pid]/stat file.
+func parseProcStat(data []byte) (procStat, error) {
+	// The command name is wrapped in parentheses and may contain spaces, so parse
+	// the remaining fields from after the last closing parenthesis.
+	content := string(data)
+	fields := strings.Fields(content[strings.LastIndexByte(content, ')')+1:])
+	// fields[0] is the state (field 3), so field N of proc(5) is fields[N-3].
+	if len(fields) < 22 {
+		return procStat{}, strconv.ErrSyntax
+	}
+
+	var (
+		stat procStat
+		err  error
+	)
+	if stat.utime, err = strconv.ParseInt(fields[11], 10, 64); err != nil {
+		return procStat{}, err
+	}
+	if stat.stime, err = strconv.ParseInt(fields[12], 10, 64); err != nil {
+		return procStat{}, err
+	}
+	if stat.rssPages, err = strconv.ParseInt(fields[21], 10, 64); err != nil {
+		return procStat{}, err
+	}
+	return stat, nil
+}
@@ -0,0 +1,62 @@
+package apw_metrics
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    procStat
+		wantErr bool
+	}{
+		{
+			name: "plain command",
+			data: "1234 (server) S 1 1234 1234 0 -1 4194560 1000 0 0 0 250 75 0 0 20 0 12 0 100 1048576 2048 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n",
+			want: procStat{utime: 250, stime: 75, rssPages: 2048},
+		},
+		{
+			name: "command with spaces and parentheses",
+			data: "42 (my (odd) cmd) R 1 42 42 0 -1 0 0 0 0 0 7 3 0 0 20 0 1 0 5 4096 16 18446744073709551615\n",
+			want: procStat{utime: 7, stime: 3, rssPages: 16},
+		},
+		{
+			name:    "too few fields",
+			data:    "1 (short) S 1 1 1",
+			wantErr: true,
+		},
+		{
+			name:    "non-numeric utime",
+			data:    "1 (bad) S 1 1 1 0 -1 0 0 0 0 0 x 3 0 0 20 0 1 0 5 4096 16 0\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProcStat([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProcStat error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseProcStat = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadProcStat(t *testing.T) {
+	if _, err := os.Stat(procSelfStat); err != nil {
+		t.Skip("/proc is not available")
+	}
+
+	stat, err := readProcStat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.rssPages <= 0 {
+		t.Errorf("rssPages = %d, want a positive resident set size", stat.rssPages)
+	}
+}
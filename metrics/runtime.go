@@ -0,0 +1,250 @@
+package apw_metrics
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	// DefaultRuntimeMetricsInterval is the minimum time between two reads of the Go runtime metrics.
+	DefaultRuntimeMetricsInterval = time.Second * 15
+
+	gcCyclesMetric       = "/gc/cycles/total:gc-cycles"
+	gcPausesMetric       = "/gc/pauses:seconds"
+	gcHeapGoalMetric     = "/gc/heap/goal:bytes"
+	heapObjectsMetric    = "/gc/heap/objects:objects"
+	heapAllocMetric      = "/memory/classes/heap/objects:bytes"
+	heapFreeMetric       = "/memory/classes/heap/free:bytes"
+	heapReleasedMetric   = "/memory/classes/heap/released:bytes"
+	memoryTotalMetric    = "/memory/classes/total:bytes"
+	goroutinesMetric     = "/sched/goroutines:goroutines"
+	cgoCallsMetric       = "/cgo/go-to-c-calls:calls"
+	schedLatenciesMetric = "/sched/latencies:seconds"
+)
+
+var schedLatencyQuantiles = []float64{0.5, 0.9, 0.99}
+
+// runtimeCollector caches a snapshot of runtime/metrics so that several instruments
+// observed in the same collection cycle share one read, and reads happen at most once per interval.
+type runtimeCollector struct {
+	mu       sync.Mutex
+	interval time.Duration
+	lastRead time.Time
+	samples  []metrics.Sample
+	index    map[string]int
+}
+
+// StartRuntimeMetrics registers observable instruments for Go runtime and process statistics
+// on the given meter. Runtime statistics are re-read at most once per interval, so collections
+// within the same interval report the cached values; a zero interval uses
+// DefaultRuntimeMetricsInterval. Process statistics are read from /proc on every collection,
+// regardless of interval, and are skipped on platforms without it.
+//
+// runtime/metrics only exposes GC pauses as a histogram, so process.runtime.go.gc.pause_total
+// is an estimate built from bucket midpoints rather than an exact total.
+func StartRuntimeMetrics(meter metric.Meter, interval time.Duration) error {
+	if interval <= 0 {
+		interval = DefaultRuntimeMetricsInterval
+	}
+
+	names := []string{
+		gcCyclesMetric, gcPausesMetric, gcHeapGoalMetric, heapObjectsMetric, heapAllocMetric,
+		heapFreeMetric, heapReleasedMetric, memoryTotalMetric, goroutinesMetric, cgoCallsMetric,
+		schedLatenciesMetric,
+	}
+	c := &runtimeCollector{interval: interval, index: make(map[string]int, len(names))}
+	for _, name := range names {
+		c.index[name] = len(c.samples)
+		c.samples = append(c.samples, metrics.Sample{Name: name})
+	}
+
+	gcCount, err := meter.Int64ObservableCounter("process.runtime.go.gc.count",
+		metric.WithDescription("Number of completed garbage collection cycles"))
+	if err != nil {
+		return err
+	}
+	gcPause, err := meter.Float64ObservableCounter("process.runtime.go.gc.pause_total",
+		metric.WithDescription("Estimated cumulative time spent in stop-the-world GC pauses"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	heapAlloc, err := meter.Int64ObservableGauge("process.runtime.go.mem.heap_alloc",
+		metric.WithDescription("Bytes of allocated heap objects"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	heapIdle, err := meter.Int64ObservableGauge("process.runtime.go.mem.heap_idle",
+		metric.WithDescription("Bytes of free heap spans, including memory released to the OS"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	heapReleased, err := meter.Int64ObservableGauge("process.runtime.go.mem.heap_released",
+		metric.WithDescription("Bytes of heap memory returned to the OS"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	heapObjects, err := meter.Int64ObservableGauge("process.runtime.go.mem.heap_objects",
+		metric.WithDescription("Number of live heap objects"),
+		metric.WithUnit("{object}"))
+	if err != nil {
+		return err
+	}
+	heapGoal, err := meter.Int64ObservableGauge("process.runtime.go.gc.heap_goal",
+		metric.WithDescription("Heap size target for the end of the GC cycle"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	memTotal, err := meter.Int64ObservableGauge("process.runtime.go.mem.total",
+		metric.WithDescription("All memory mapped by the Go runtime"),
+		metric.WithUnit("By"))
+	if err != nil {
+		return err
+	}
+	goroutines, err := meter.Int64ObservableGauge("process.runtime.go.goroutines",
+		metric.WithDescription("Number of live goroutines"),
+		metric.WithUnit("{goroutine}"))
+	if err != nil {
+		return err
+	}
+	cgoCalls, err := meter.Int64ObservableCounter("process.runtime.go.cgo.calls",
+		metric.WithDescription("Number of calls made from Go to C"),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return err
+	}
+	schedLatency, err := meter.Float64ObservableGauge("process.runtime.go.sched.latency",
+		metric.WithDescription("Estimated time goroutines spent runnable before running, by quantile"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.refresh()
+
+		o.ObserveInt64(gcCount, c.uint64Value(gcCyclesMetric))
+		o.ObserveFloat64(gcPause, histogramSum(c.histogramValue(gcPausesMetric)))
+		o.ObserveInt64(heapAlloc, c.uint64Value(heapAllocMetric))
+		o.ObserveInt64(heapIdle, c.uint64Value(heapFreeMetric)+c.uint64Value(heapReleasedMetric))
+		o.ObserveInt64(heapReleased, c.uint64Value(heapReleasedMetric))
+		o.ObserveInt64(heapObjects, c.uint64Value(heapObjectsMetric))
+		o.ObserveInt64(heapGoal, c.uint64Value(gcHeapGoalMetric))
+		o.ObserveInt64(memTotal, c.uint64Value(memoryTotalMetric))
+		o.ObserveInt64(goroutines, c.uint64Value(goroutinesMetric))
+		o.ObserveInt64(cgoCalls, c.uint64Value(cgoCallsMetric))
+
+		latencies := c.histogramValue(schedLatenciesMetric)
+		for _, q := range schedLatencyQuantiles {
+			o.ObserveFloat64(schedLatency, histogramQuantile(latencies, q),
+				metric.WithAttributes(attribute.Float64("quantile", q)))
+		}
+		return nil
+	}, gcCount, gcPause, heapAlloc, heapIdle, heapReleased, heapObjects, heapGoal, memTotal, goroutines, cgoCalls, schedLatency)
+	if err != nil {
+		return err
+	}
+
+	return startProcessMetrics(meter)
+}
+
+// refresh re-reads the runtime metrics when the cached snapshot is older than the interval.
+func (c *runtimeCollector) refresh() {
+	now := time.Now()
+	if !c.lastRead.IsZero() && now.Sub(c.lastRead) < c.interval {
+		return
+	}
+	metrics.Read(c.samples)
+	c.lastRead = now
+}
+
+func (c *runtimeCollector) uint64Value(name string) int64 {
+	v := c.samples[c.index[name]].Value
+	if v.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	u := v.Uint64()
+	if u > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(u)
+}
+
+func (c *runtimeCollector) histogramValue(name string) *metrics.Float64Histogram {
+	v := c.samples[c.index[name]].Value
+	if v.Kind() != metrics.KindFloat64Histogram {
+		return nil
+	}
+	return v.Float64Histogram()
+}
+
+// histogramSum estimates the total of all values in h using each bucket's midpoint, or its
+// finite bound for the unbounded first and last buckets.
+func histogramSum(h *metrics.Float64Histogram) float64 {
+	if h == nil {
+		return 0
+	}
+
+	var sum float64
+	for i, count := range h.Counts {
+		if count == 0 {
+			continue
+		}
+		lower, upper := h.Buckets[i], h.Buckets[i+1]
+		value := lower + (upper-lower)/2
+		switch {
+		case math.IsInf(lower, -1):
+			value = upper
+		case math.IsInf(upper, 1):
+			value = lower
+		}
+		sum += float64(count) * value
+	}
+	return sum
+}
+
+// histogramQuantile estimates the q-quantile of h, returning the bucket boundary that
+// contains it.
+func histogramQuantile(h *metrics.Float64Histogram, q float64) float64 {
+	if h == nil {
+		return 0
+	}
+
+	var total uint64
+	for _, count := range h.Counts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, count := range h.Counts {
+		seen += count
+		if seen >= rank {
+			return bucketValue(h, i)
+		}
+	}
+	return bucketValue(h, len(h.Counts)-1)
+}
+
+func bucketValue(h *metrics.Float64Histogram, i int) float64 {
+	lower, upper := h.Buckets[i], h.Buckets[i+1]
+	if math.IsInf(lower, -1) {
+		return upper
+	}
+	return lower
+}
@@ -0,0 +1,158 @@
+package apw_metrics
+
+import (
+	"context"
+	"math"
+	"os"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestHistogramSumUsesBucketMidpoints(t *testing.T) {
+	h := &metrics.Float64Histogram{
+		Counts:  []uint64{1, 2, 3},
+		Buckets: []float64{math.Inf(-1), 1, 3, math.Inf(1)},
+	}
+
+	// 1*1 (upper bound of the first bucket) + 2*2 (midpoint) + 3*3 (lower bound of the last).
+	if got, want := histogramSum(h), 14.0; got != want {
+		t.Fatalf("histogramSum = %v, want %v", got, want)
+	}
+}
+
+func TestHistogramSumNil(t *testing.T) {
+	if got := histogramSum(nil); got != 0 {
+		t.Fatalf("histogramSum(nil) = %v, want 0", got)
+	}
+}
+
+func TestStartRuntimeMetricsRegistersInstruments(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	if err := StartRuntimeMetrics(mp.Meter("test"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = m.Data
+		}
+	}
+
+	positive := []string{
+		"process.runtime.go.gc.heap_goal",
+		"process.runtime.go.mem.heap_alloc",
+		"process.runtime.go.mem.heap_objects",
+		"process.runtime.go.mem.total",
+		"process.runtime.go.goroutines",
+	}
+	present := []string{
+		"process.runtime.go.gc.count",
+		"process.runtime.go.gc.pause_total",
+		"process.runtime.go.mem.heap_idle",
+		"process.runtime.go.mem.heap_released",
+		"process.runtime.go.cgo.calls",
+		"process.runtime.go.sched.latency",
+	}
+	if _, err := os.Stat(procSelfStat); err == nil {
+		positive = append(positive, "process.memory.usage")
+		present = append(present, "process.cpu.time", "process.open_file_descriptor.count")
+	}
+
+	for _, name := range append(positive, present...) {
+		if _, ok := found[name]; !ok {
+			t.Errorf("%s was not collected", name)
+		}
+	}
+	for _, name := range positive {
+		if v, ok := firstValue(found[name]); ok && v <= 0 {
+			t.Errorf("%s = %v, want a positive value", name, v)
+		}
+	}
+
+	if data, ok := found["process.runtime.go.sched.latency"].(metricdata.Gauge[float64]); ok {
+		if len(data.DataPoints) != len(schedLatencyQuantiles) {
+			t.Errorf("sched.latency has %d points, want one per quantile", len(data.DataPoints))
+		}
+	}
+}
+
+func TestOpenFileDescriptorCountExcludesReadDir(t *testing.T) {
+	if _, err := os.Stat(procSelfStat); err != nil {
+		t.Skip("/proc is not available")
+	}
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	if err := startProcessMetrics(mp.Meter("test")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep a known file open so the count can be compared before and after closing it.
+	f, err := os.Open(procSelfStat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := collectOpenFDs(t, reader)
+	f.Close()
+	closed := collectOpenFDs(t, reader)
+
+	if open-closed != 1 {
+		t.Errorf("open_file_descriptor.count went from %d to %d after closing one file", open, closed)
+	}
+	entries, err := os.ReadDir(procSelfFD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(entries) - 1); closed != want {
+		t.Errorf("open_file_descriptor.count = %d, want %d without ReadDir's own descriptor", closed, want)
+	}
+}
+
+func collectOpenFDs(t *testing.T, reader *sdkmetric.ManualReader) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == "process.open_file_descriptor.count" {
+				return m.Data.(metricdata.Gauge[int64]).DataPoints[0].Value
+			}
+		}
+	}
+	t.Fatal("process.open_file_descriptor.count was not collected")
+	return 0
+}
+
+// firstValue returns the value of the first data point of an int64 or float64 sum or gauge.
+func firstValue(data metricdata.Aggregation) (float64, bool) {
+	switch d := data.(type) {
+	case metricdata.Sum[int64]:
+		if len(d.DataPoints) > 0 {
+			return float64(d.DataPoints[0].Value), true
+		}
+	case metricdata.Sum[float64]:
+		if len(d.DataPoints) > 0 {
+			return d.DataPoints[0].Value, true
+		}
+	case metricdata.Gauge[int64]:
+		if len(d.DataPoints) > 0 {
+			return float64(d.DataPoints[0].Value), true
+		}
+	case metricdata.Gauge[float64]:
+		if len(d.DataPoints) > 0 {
+			return d.DataPoints[0].Value, true
+		}
+	}
+	return 0, false
+}
@@ -7,9 +7,13 @@ import (
 	"go.opentelemetry.io/otel/metric"
 )
 
+// middlewareScopeName is the instrumentation scope of the metrics recorded by this package.
+const middlewareScopeName = "otel-library/middleware"
+
 // Gin middleware to handle metrics collection for each endpoint.
 // The middleware increments a counter each time an endpoint is hit, ensuring that metrics are consistently collected across all endpoints.
 func MetricsMiddleware(metrics apw_metrics.OtelMetric) gin.HandlerFunc {
+	metrics = metrics.Meter(middlewareScopeName, "")
 	return func(c *gin.Context) {
 		counter, _ := metrics.CreateCounter(c.Request.URL.Path,
 			metric.WithDescription("Total number calling this api"),
@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"otel-library/errs"
+	apw_tracing "otel-library/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceResponseHeader is the W3C Trace Context response header, formatted like traceparent.
+const TraceResponseHeader = "traceresponse"
+
+type traceResponseConfig struct {
+	traceIDHeader string
+	sampledOnly   bool
+	tracing       apw_tracing.OtelTracing
+}
+
+// TraceResponseOption configures the trace response middleware and NewErrorBody.
+type TraceResponseOption func(*traceResponseConfig)
+
+// WithTraceIDHeader also writes the bare trace ID in the named header, for example "X-Trace-Id".
+func WithTraceIDHeader(name string) TraceResponseOption {
+	return func(c *traceResponseConfig) {
+		c.traceIDHeader = name
+	}
+}
+
+// WithSampledOnly only exposes trace IDs of sampled requests, whose traces can actually be found.
+func WithSampledOnly() TraceResponseOption {
+	return func(c *traceResponseConfig) {
+		c.sampledOnly = true
+	}
+}
+
+// WithServerSpan makes the middleware start a server span, continuing the trace of the incoming
+// headers, for requests that reach it without one. Handlers then start their spans as children
+// of it from the request context, and the response headers name it.
+func WithServerSpan(tracing apw_tracing.OtelTracing) TraceResponseOption {
+	return func(c *traceResponseConfig) {
+		c.tracing = tracing
+	}
+}
+
+func newTraceResponseConfig(opts []TraceResponseOption) traceResponseConfig {
+	var cfg traceResponseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// spanContext returns the span context of ctx when it may be exposed to the caller.
+func (c traceResponseConfig) spanContext(ctx context.Context) (trace.SpanContext, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() || (c.sampledOnly && !sc.IsSampled()) {
+		return trace.SpanContext{}, false
+	}
+	return sc, true
+}
+
+// setHeaders writes the trace response headers for the span in ctx.
+func (c traceResponseConfig) setHeaders(ctx context.Context, h http.Header) {
+	sc, ok := c.spanContext(ctx)
+	if !ok {
+		return
+	}
+	h.Set(TraceResponseHeader, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-"+sc.TraceFlags().String())
+	if c.traceIDHeader != "" {
+		h.Set(c.traceIDHeader, sc.TraceID().String())
+	}
+}
+
+// startServerSpan starts the request's server span when WithServerSpan is set and ctx holds no
+// span yet. It returns a nil span otherwise.
+func (c traceResponseConfig) startServerSpan(r *http.Request, route string) (context.Context, trace.Span) {
+	ctx := r.Context()
+	if c.tracing == nil || trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil
+	}
+
+	name := r.Method
+	attrs := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)}
+	if route != "" {
+		name += " " + route
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+	ctx = c.tracing.Extract(ctx, r.Header)
+	return c.tracing.StartSpan(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// endServerSpan records the response status on a span started by startServerSpan and ends it.
+func (c traceResponseConfig) endServerSpan(span trace.Span, statusCode int) {
+	if span == nil {
+		return
+	}
+	c.tracing.SetHTTPStatus(span, statusCode, trace.SpanKindServer)
+	c.tracing.EndSpan(span)
+}
+
+// TraceResponseMiddleware writes the traceresponse header, and optionally a custom trace ID
+// header, on every response. The headers are set when the response is written, from the span
+// in the request context at that time, so a handler that replaces c.Request with its own span's
+// context is covered. Use WithServerSpan when no earlier middleware starts the request span.
+func TraceResponseMiddleware(opts ...TraceResponseOption) gin.HandlerFunc {
+	cfg := newTraceResponseConfig(opts)
+	return func(c *gin.Context) {
+		ctx, span := cfg.startServerSpan(c.Request, c.FullPath())
+		if span != nil {
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		w := &traceResponseWriter{ResponseWriter: c.Writer}
+		w.setHeaders = func() { cfg.setHeaders(c.Request.Context(), w.Header()) }
+		c.Writer = w
+		c.Next()
+		// gin writes the header of an empty response after the chain returns, bypassing w.
+		if !w.Written() {
+			w.setHeadersOnce()
+		}
+		cfg.endServerSpan(span, w.Status())
+	}
+}
+
+// traceResponseWriter sets the trace response headers right before the response header is sent.
+type traceResponseWriter struct {
+	gin.ResponseWriter
+	setHeaders func()
+	headersSet bool
+}
+
+func (w *traceResponseWriter) setHeadersOnce() {
+	if !w.headersSet {
+		w.headersSet = true
+		w.setHeaders()
+	}
+}
+
+func (w *traceResponseWriter) WriteHeaderNow() {
+	w.setHeadersOnce()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *traceResponseWriter) Write(data []byte) (int, error) {
+	w.setHeadersOnce()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *traceResponseWriter) WriteString(s string) (int, error) {
+	w.setHeadersOnce()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *traceResponseWriter) Flush() {
+	w.setHeadersOnce()
+	w.ResponseWriter.Flush()
+}
+
+// TraceResponseHandler is the net/http counterpart of TraceResponseMiddleware. A net/http
+// handler cannot hand its context back, so the headers name the span in the request context
+// when the request arrives, or the server span started with WithServerSpan.
+func TraceResponseHandler(next http.Handler, opts ...TraceResponseOption) http.Handler {
+	cfg := newTraceResponseConfig(opts)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := cfg.startServerSpan(r, "")
+		cfg.setHeaders(ctx, w.Header())
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		cfg.endServerSpan(span, rec.status)
+	})
+}
+
+// statusRecorder remembers the status code written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.status = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// ErrorBody is the JSON body of an error response built from an *errs.ErrorService.
+type ErrorBody struct {
+	Status  string `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	TraceID string `json:"trace_id,omitempty"`
+}
+
+// NewErrorBody builds the error response body for err, including the trace ID of the span in
+// ctx so that callers can quote it when reporting the failure.
+func NewErrorBody(ctx context.Context, err *errs.ErrorService, opts ...TraceResponseOption) ErrorBody {
+	body := ErrorBody{
+		Status:  err.StatusText,
+		Code:    err.ErrorCode,
+		Message: err.ErrorMessage,
+	}
+	if sc, ok := newTraceResponseConfig(opts).spanContext(ctx); ok {
+		body.TraceID = sc.TraceID().String()
+	}
+	return body
+}
+
+// AbortWithErrorService aborts the request with err's status code and its NewErrorBody as JSON.
+// Statuses that cannot carry a body, such as 204 and 304, are sent without one.
+func AbortWithErrorService(c *gin.Context, err *errs.ErrorService, opts ...TraceResponseOption) {
+	if !bodyAllowed(err.StatusCode) {
+		c.AbortWithStatus(err.StatusCode)
+		return
+	}
+	c.AbortWithStatusJSON(err.StatusCode, NewErrorBody(c.Request.Context(), err, opts...))
+}
+
+// WriteErrorService is the net/http counterpart of AbortWithErrorService.
+func WriteErrorService(w http.ResponseWriter, r *http.Request, err *errs.ErrorService, opts ...TraceResponseOption) {
+	if !bodyAllowed(err.StatusCode) {
+		w.WriteHeader(err.StatusCode)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(err.StatusCode)
+	_ = json.NewEncoder(w).Encode(NewErrorBody(r.Context(), err, opts...))
+}
+
+// bodyAllowed reports whether a response with statusCode may have a body, see RFC 9110.
+func bodyAllowed(statusCode int) bool {
+	switch {
+	case statusCode >= 100 && statusCode < 200:
+		return false
+	case statusCode == http.StatusNoContent, statusCode == http.StatusNotModified:
+		return false
+	}
+	return true
+}
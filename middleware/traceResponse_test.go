@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"otel-library/errs"
+	apw_logging "otel-library/logs"
+	apw_tracing "otel-library/tracing"
+
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracing() (apw_tracing.OtelTracing, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return apw_tracing.NewTracing(tp.Tracer("test"), apw_logging.NewNoopLogging(), apw_tracing.WithTracerProvider(tp)), recorder
+}
+
+func newTestRouter(opts ...TraceResponseOption) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(TraceResponseMiddleware(opts...))
+	return r
+}
+
+// traceResponse parses the traceresponse header into its trace and span IDs.
+func traceResponse(t *testing.T, h http.Header) (traceID, spanID string) {
+	t.Helper()
+	parts := strings.Split(h.Get(TraceResponseHeader), "-")
+	if len(parts) != 4 {
+		t.Fatalf("%s = %q, want version-traceid-spanid-flags", TraceResponseHeader, h.Get(TraceResponseHeader))
+	}
+	return parts[1], parts[2]
+}
+
+func TestTraceResponseMiddlewareStartsServerSpan(t *testing.T) {
+	tracing, recorder := newTestTracing()
+	r := newTestRouter(WithServerSpan(tracing), WithTraceIDHeader("X-Trace-Id"))
+	r.GET("/users/:id", func(c *gin.Context) {
+		_, span := tracing.StartSpan(c.Request.Context(), "load user")
+		defer span.End()
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	traceID, spanID := traceResponse(t, w.Header())
+	if w.Header().Get("X-Trace-Id") != traceID {
+		t.Errorf("X-Trace-Id = %q, want %q", w.Header().Get("X-Trace-Id"), traceID)
+	}
+
+	var server, child sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "GET /users/:id":
+			server = span
+		case "load user":
+			child = span
+		}
+	}
+	if server == nil || child == nil {
+		t.Fatalf("got spans %v, want the server span and the handler's span", recorder.Ended())
+	}
+	if server.SpanKind() != trace.SpanKindServer || server.SpanContext().SpanID().String() != spanID {
+		t.Errorf("header names span %s, want the server span %s", spanID, server.SpanContext().SpanID())
+	}
+	if child.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Error("handler span is not a child of the server span")
+	}
+	found := false
+	for _, attr := range server.Attributes() {
+		found = found || attr == semconv.HTTPResponseStatusCode(http.StatusOK)
+	}
+	if !found {
+		t.Errorf("server span attributes %v lack the response status code", server.Attributes())
+	}
+}
+
+func TestTraceResponseMiddlewareUsesHandlerSpan(t *testing.T) {
+	tracing, _ := newTestTracing()
+	r := newTestRouter()
+	var handlerSpan trace.SpanContext
+	r.GET("/", func(c *gin.Context) {
+		ctx, span := tracing.StartSpan(c.Request.Context(), "handler")
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		handlerSpan = span.SpanContext()
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if traceID, spanID := traceResponse(t, w.Header()); traceID != handlerSpan.TraceID().String() || spanID != handlerSpan.SpanID().String() {
+		t.Errorf("header names %s/%s, want the handler span %s/%s", traceID, spanID, handlerSpan.TraceID(), handlerSpan.SpanID())
+	}
+}
+
+func TestTraceResponseMiddlewareEmptyResponse(t *testing.T) {
+	tracing, _ := newTestTracing()
+	r := newTestRouter(WithServerSpan(tracing))
+	r.DELETE("/", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/", nil))
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", w.Code)
+	}
+	traceResponse(t, w.Header())
+}
+
+func TestTraceResponseHandlerContinuesIncomingTrace(t *testing.T) {
+	tracing, _ := newTestTracing()
+	const incomingTraceID = "0af7651916cd43dd8448eb211c80319c"
+	h := TraceResponseHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("handler context holds no span")
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}), WithServerSpan(tracing))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("traceparent", "00-"+incomingTraceID+"-b7ad6b7169203331-01")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if traceID, _ := traceResponse(t, w.Header()); traceID != incomingTraceID {
+		t.Errorf("trace ID = %s, want the incoming %s", traceID, incomingTraceID)
+	}
+}
+
+func TestTraceResponseSampledOnly(t *testing.T) {
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01},
+		SpanID:  trace.SpanID{0x02},
+	}))
+	h := TraceResponseHandler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}), WithSampledOnly())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if got := w.Header().Get(TraceResponseHeader); got != "" {
+		t.Errorf("%s = %q for an unsampled trace, want none", TraceResponseHeader, got)
+	}
+}
+
+func TestWriteErrorService(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		wantBody bool
+	}{
+		{"not found", http.StatusNotFound, true},
+		{"no content", http.StatusNoContent, false},
+		{"not modified", http.StatusNotModified, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := &errs.ErrorService{StatusCode: tt.status, StatusText: http.StatusText(tt.status), ErrorCode: "CODE", ErrorMessage: "message"}
+			w := httptest.NewRecorder()
+			WriteErrorService(w, httptest.NewRequest(http.MethodGet, "/", nil), err)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if hasBody := w.Body.Len() > 0; hasBody != tt.wantBody {
+				t.Errorf("body = %q, want body: %v", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantBody && !strings.Contains(w.Body.String(), `"code":"CODE"`) {
+				t.Errorf("body = %q, want the error code", w.Body.String())
+			}
+		})
+	}
+}
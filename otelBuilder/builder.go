@@ -2,22 +2,30 @@ package otelBuilder
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	apw_logging "otel-library/logs"
 	apw_metrics "otel-library/metrics"
 	apw_tracing "otel-library/tracing"
+	"time"
 
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 )
 
+// startRuntimeMetrics registers the runtime metrics; it is a variable so that tests can make
+// Build fail after the providers are created.
+var startRuntimeMetrics = apw_metrics.StartRuntimeMetrics
+
 const (
-	tracerName = "default-tracer"
-	meterName  = "default-meter"
+	tracerName       = "default-tracer"
+	meterName        = "default-meter"
+	runtimeMeterName = "otel-library/runtime"
 )
 
 type Header map[string]string
@@ -29,14 +37,43 @@ type OtelBuilder struct {
 	traceExporterOpts  []otlptracehttp.Option
 	metricExporterOpts []otlpmetrichttp.Option
 	useConsoleExporter bool
+	spanLimits         trace.SpanLimits
+	enabled            bool
+	endpointURL        string
+	insecure           bool
+	headers            Header
+	tlsConfig          *tls.Config
+	startupCheck       *startupCheck
+	runtimeMetrics     bool
+	runtimeInterval    time.Duration
+	propagator         propagation.TextMapPropagator
+	baggageKeys        []string
+	baggageMaxEntries  int
+	baggageMaxBytes    int
+	profilerLabels     *apw_tracing.ProfilerLabels
+	spanProcessors     []trace.SpanProcessor
+	redMetrics         bool
+	redDimensions      []string
+	slowOperations     *SlowOperationConfig
 }
 
 func NewOtelBuilder() *OtelBuilder {
-	return &OtelBuilder{}
+	return &OtelBuilder{
+		spanLimits: trace.NewSpanLimits(),
+		enabled:    !sdkDisabledByEnv(),
+	}
+}
+
+// WithEnabled turns telemetry on or off. When disabled, Build returns no-op tracing and
+// metrics without creating any exporter. The default honours OTEL_SDK_DISABLED.
+func (b *OtelBuilder) WithEnabled(enabled bool) *OtelBuilder {
+	b.enabled = enabled
+	return b
 }
 
 // WithInsecure configures whether to use an insecure (non-TLS) connection to the OTLP endpoint.
 func (b *OtelBuilder) WithInsecure(insecure bool) *OtelBuilder {
+	b.insecure = insecure
 	if insecure {
 		b.traceExporterOpts = append(b.traceExporterOpts, otlptracehttp.WithInsecure())
 		b.metricExporterOpts = append(b.metricExporterOpts, otlpmetrichttp.WithInsecure())
@@ -48,6 +85,7 @@ func (b *OtelBuilder) WithInsecure(insecure bool) *OtelBuilder {
 // WithEndpoint sets the OTLP endpoint URL for both tracing and metrics.
 func (b *OtelBuilder) WithEndpointURL(otlpEndpoint string) *OtelBuilder {
 	if otlpEndpoint != "" {
+		b.endpointURL = otlpEndpoint
 		b.traceExporterOpts = append(b.traceExporterOpts, otlptracehttp.WithEndpointURL(otlpEndpoint))
 		b.metricExporterOpts = append(b.metricExporterOpts, otlpmetrichttp.WithEndpointURL(otlpEndpoint))
 	}
@@ -60,6 +98,7 @@ func (b *OtelBuilder) WithHeaders(headers Header) *OtelBuilder {
 	for key, value := range headers {
 		headerMap[key] = value
 	}
+	b.headers = headerMap
 	b.traceExporterOpts = append(b.traceExporterOpts, otlptracehttp.WithHeaders(headerMap))
 	b.metricExporterOpts = append(b.metricExporterOpts, otlpmetrichttp.WithHeaders(headerMap))
 	return b
@@ -72,6 +111,78 @@ func (b *OtelBuilder) WithAuthHeader(token string) *OtelBuilder {
 	})
 }
 
+// WithTLSClientConfig sets the TLS configuration used to connect to the OTLP endpoint.
+func (b *OtelBuilder) WithTLSClientConfig(cfg *tls.Config) *OtelBuilder {
+	if cfg != nil {
+		b.tlsConfig = cfg
+		b.traceExporterOpts = append(b.traceExporterOpts, otlptracehttp.WithTLSClientConfig(cfg))
+		b.metricExporterOpts = append(b.metricExporterOpts, otlpmetrichttp.WithTLSClientConfig(cfg))
+	}
+	return b
+}
+
+// WithPropagator sets the propagator used by the tracing helpers to inject and extract context.
+// The default propagates W3C trace context and baggage; a nil propagator keeps the default.
+func (b *OtelBuilder) WithPropagator(propagator propagation.TextMapPropagator) *OtelBuilder {
+	b.propagator = propagator
+	return b
+}
+
+// WithBaggagePromotion copies the listed baggage keys onto every new span, onto the attributes
+// of every metric measurement and onto the log entries written by the tracing returned from
+// Build. Build cannot change the caller's own logger: create it with
+// apw_logging.WithBaggageKeys, or wrap it with apw_logging.PromoteBaggage, to add the keys to
+// its entries too.
+func (b *OtelBuilder) WithBaggagePromotion(keys ...string) *OtelBuilder {
+	b.baggageKeys = append(b.baggageKeys, keys...)
+	return b
+}
+
+// WithBaggageLimits sets the maximum number of baggage entries and the maximum encoded size in
+// bytes enforced by the tracing SetBaggage helper. Non-positive values keep the W3C defaults.
+func (b *OtelBuilder) WithBaggageLimits(maxEntries, maxBytes int) *OtelBuilder {
+	b.baggageMaxEntries = maxEntries
+	b.baggageMaxBytes = maxBytes
+	return b
+}
+
+// WithProfilerLabels applies pprof labels to goroutines while they run a span, so that CPU
+// profile samples can be tied back to spans. See apw_tracing.WithProfilerLabels.
+func (b *OtelBuilder) WithProfilerLabels(labels apw_tracing.ProfilerLabels) *OtelBuilder {
+	b.profilerLabels = &labels
+	return b
+}
+
+// WithSpanProcessor registers an additional span processor, such as the tracez debug page
+// processor, on the built TracerProvider.
+func (b *OtelBuilder) WithSpanProcessor(processor trace.SpanProcessor) *OtelBuilder {
+	if processor != nil {
+		b.spanProcessors = append(b.spanProcessors, processor)
+	}
+	return b
+}
+
+// WithREDMetrics derives request count (span.calls), error count (span.errors) and duration
+// (span.duration) metrics from every ended span, keyed by span name, kind and status plus the
+// listed span attributes, for example "http.route".
+//
+// With the default configuration no exemplars are recorded. The metric SDK only records
+// exemplars, which link each span.duration measurement to the trace and span that produced it,
+// when the experimental OTEL_GO_X_EXEMPLAR environment variable is set to true before Build is
+// called; Build logs a warning when it is not.
+func (b *OtelBuilder) WithREDMetrics(dimensions ...string) *OtelBuilder {
+	b.redMetrics = true
+	b.redDimensions = append(b.redDimensions, dimensions...)
+	return b
+}
+
+// WithSlowOperationDetector warns through OtelLogging, and adds a slow_operation event to the
+// span, when a span has been running longer than its configured threshold.
+func (b *OtelBuilder) WithSlowOperationDetector(cfg SlowOperationConfig) *OtelBuilder {
+	b.slowOperations = &cfg
+	return b
+}
+
 // WithServiceName sets the name of the service that will be reported in tracing and metrics data.
 func (b *OtelBuilder) WithServiceName(serviceName string) *OtelBuilder {
 	if serviceName != "" {
@@ -98,19 +209,99 @@ func (b *OtelBuilder) WithConsoleExporter() *OtelBuilder {
 	return b
 }
 
+// WithRuntimeMetrics enables Go runtime and process metrics (GC, heap, goroutines, CGo calls,
+// scheduler latency, CPU, RSS and open file descriptors) on the built MeterProvider.
+func (b *OtelBuilder) WithRuntimeMetrics() *OtelBuilder {
+	b.runtimeMetrics = true
+	return b
+}
+
+// WithRuntimeMetricsInterval sets the minimum time between two reads of the Go runtime metrics.
+// It does not throttle the process metrics, which are read on every collection.
+func (b *OtelBuilder) WithRuntimeMetricsInterval(interval time.Duration) *OtelBuilder {
+	b.runtimeInterval = interval
+	return b
+}
+
+// WithSpanLimits sets several span limits at once. Only the non-zero fields of limits are
+// applied; the others keep their current value, which defaults to the OTEL_SPAN_* environment
+// variables or the SDK defaults.
+func (b *OtelBuilder) WithSpanLimits(limits trace.SpanLimits) *OtelBuilder {
+	mergeLimit(&b.spanLimits.AttributeValueLengthLimit, limits.AttributeValueLengthLimit)
+	mergeLimit(&b.spanLimits.AttributeCountLimit, limits.AttributeCountLimit)
+	mergeLimit(&b.spanLimits.EventCountLimit, limits.EventCountLimit)
+	mergeLimit(&b.spanLimits.LinkCountLimit, limits.LinkCountLimit)
+	mergeLimit(&b.spanLimits.AttributePerEventCountLimit, limits.AttributePerEventCountLimit)
+	mergeLimit(&b.spanLimits.AttributePerLinkCountLimit, limits.AttributePerLinkCountLimit)
+	return b
+}
+
+// mergeLimit overwrites dst with limit unless limit is zero, which means "not set".
+func mergeLimit(dst *int, limit int) {
+	if limit != 0 {
+		*dst = limit
+	}
+}
+
+// WithMaxAttributes sets the maximum number of attributes a span can hold. A negative value means no limit.
+func (b *OtelBuilder) WithMaxAttributes(limit int) *OtelBuilder {
+	b.spanLimits.AttributeCountLimit = limit
+	return b
+}
+
+// WithMaxEvents sets the maximum number of events a span can hold. A negative value means no limit.
+func (b *OtelBuilder) WithMaxEvents(limit int) *OtelBuilder {
+	b.spanLimits.EventCountLimit = limit
+	return b
+}
+
+// WithMaxLinks sets the maximum number of links a span can hold. A negative value means no limit.
+func (b *OtelBuilder) WithMaxLinks(limit int) *OtelBuilder {
+	b.spanLimits.LinkCountLimit = limit
+	return b
+}
+
+// WithMaxAttributesPerEvent sets the maximum number of attributes per span event. A negative value means no limit.
+func (b *OtelBuilder) WithMaxAttributesPerEvent(limit int) *OtelBuilder {
+	b.spanLimits.AttributePerEventCountLimit = limit
+	return b
+}
+
+// WithAttributeValueLengthLimit sets the maximum length in bytes of string attribute values.
+// Longer values are truncated on a UTF-8 boundary and marked with a truncation suffix.
+// A negative value means no limit. Zero would truncate every value to "" and is ignored.
+func (b *OtelBuilder) WithAttributeValueLengthLimit(limit int) *OtelBuilder {
+	mergeLimit(&b.spanLimits.AttributeValueLengthLimit, limit)
+	return b
+}
+
 // Build creates and returns OtelTracing and OtelMetrics instances using the configured options.
 func (b *OtelBuilder) Build(ctx context.Context, l apw_logging.OtelLogging) (apw_tracing.OtelTracing, apw_metrics.OtelMetric, error) {
+	if !b.enabled {
+		return newNoopTracing(l), newNoopMetrics(), nil
+	}
+	l = apw_logging.PromoteBaggage(l, b.baggageKeys...)
+
 	var traceExporter trace.SpanExporter
 	var metricExporter metric.Exporter
 	var err error
 
-	if b.useConsoleExporter {
+	useConsoleExporter := b.useConsoleExporter
+	if !useConsoleExporter && b.startupCheck != nil {
+		useConsoleExporter, err = b.runStartupCheck(ctx, l)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if useConsoleExporter {
 		traceExporter, err = newConsoleTraceExporter()
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to create console trace exporter: %w", err)
 		}
 		metricExporter, err = newConsoleMetricExporter()
 		if err != nil {
+			shutdown(ctx, traceExporter.Shutdown)
 			return nil, nil, fmt.Errorf("failed to create console metric exporter: %w", err)
 		}
 	} else {
@@ -120,20 +311,103 @@ func (b *OtelBuilder) Build(ctx context.Context, l apw_logging.OtelLogging) (apw
 		}
 		metricExporter, err = otlpmetrichttp.New(ctx, b.metricExporterOpts...)
 		if err != nil {
+			shutdown(ctx, traceExporter.Shutdown)
 			return nil, nil, fmt.Errorf("failed to create OTLP metric exporter with options %v: %w", b.metricExporterOpts, err)
 		}
 	}
 
 	resourceOpts := resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(b.serviceName))
-	tracerProvider := trace.NewTracerProvider(
+	meterProvider := metric.NewMeterProvider(
+		metric.WithReader(metric.NewPeriodicReader(metricExporter, b.metricOpts...)),
+		metric.WithResource(resourceOpts),
+	)
+
+	var redProcessor *redMetricsProcessor
+	if b.redMetrics {
+		redProcessor, err = newREDMetricsProcessor(meterProvider.Meter(redMeterName), b.redDimensions)
+		if err != nil {
+			shutdown(ctx, meterProvider.Shutdown, traceExporter.Shutdown)
+			return nil, nil, fmt.Errorf("failed to create RED metrics: %w", err)
+		}
+		if !exemplarsEnabled() {
+			l.Warnf("RED metrics are recorded without exemplars; set %s=true to link them to spans", exemplarEnv)
+		}
+	}
+
+	tracerProviderOpts := []trace.TracerProviderOption{
 		trace.WithBatcher(traceExporter, b.traceOpts...),
 		trace.WithResource(resourceOpts),
+		trace.WithRawSpanLimits(b.spanLimits),
+	}
+	for _, processor := range b.spanProcessors {
+		tracerProviderOpts = append(tracerProviderOpts, trace.WithSpanProcessor(processor))
+	}
+	if redProcessor != nil {
+		tracerProviderOpts = append(tracerProviderOpts, trace.WithSpanProcessor(redProcessor))
+	}
+	if b.slowOperations != nil {
+		tracerProviderOpts = append(tracerProviderOpts, trace.WithSpanProcessor(newSlowOperationDetector(*b.slowOperations, l)))
+	}
+	tracerProvider := trace.NewTracerProvider(tracerProviderOpts...)
+
+	if b.runtimeMetrics {
+		if err := startRuntimeMetrics(meterProvider.Meter(runtimeMeterName), b.runtimeInterval); err != nil {
+			shutdown(ctx, tracerProvider.Shutdown, meterProvider.Shutdown)
+			return nil, nil, fmt.Errorf("failed to register runtime metrics: %w", err)
+		}
+	}
+
+	tracing := apw_tracing.NewTracing(
+		tracerProvider.Tracer(b.defaultScopeName(tracerName)),
+		l,
+		b.tracingOptions(tracerProvider)...,
 	)
 
-	meterProvider := metric.NewMeterProvider(
-		metric.WithReader(metric.NewPeriodicReader(metricExporter, b.metricOpts...)),
-		metric.WithResource(resourceOpts),
+	metrics := apw_metrics.NewMetric(
+		meterProvider.Meter(b.defaultScopeName(meterName)),
+		apw_metrics.WithMeterProvider(meterProvider),
+		apw_metrics.WithBaggageKeys(b.baggageKeys...),
 	)
 
-	return apw_tracing.NewTracing(tracerProvider.Tracer(b.serviceName), l), apw_metrics.NewMetric(meterProvider.Meter(b.serviceName)), nil
+	return tracing, metrics, nil
+}
+
+// shutdown releases what Build created before failing, so that no exporter, reader or span
+// processor goroutine outlives the failed Build. Their errors are dropped in favour of the
+// error that made Build fail.
+func shutdown(ctx context.Context, shutdowns ...func(context.Context) error) {
+	ctx = context.WithoutCancel(ctx)
+	for _, s := range shutdowns {
+		_ = s(ctx)
+	}
+}
+
+// tracingOptions returns the apw_tracing options derived from the builder configuration.
+func (b *OtelBuilder) tracingOptions(tracerProvider *trace.TracerProvider) []apw_tracing.Option {
+	opts := []apw_tracing.Option{
+		apw_tracing.WithTracerProvider(tracerProvider),
+		apw_tracing.WithAttributeValueLengthLimit(b.spanLimits.AttributeValueLengthLimit),
+	}
+	if b.propagator != nil {
+		opts = append(opts, apw_tracing.WithPropagator(b.propagator))
+	}
+	if len(b.baggageKeys) > 0 {
+		opts = append(opts, apw_tracing.WithBaggagePromotion(b.baggageKeys...))
+	}
+	if b.baggageMaxEntries > 0 || b.baggageMaxBytes > 0 {
+		opts = append(opts, apw_tracing.WithBaggageLimits(b.baggageMaxEntries, b.baggageMaxBytes))
+	}
+	if b.profilerLabels != nil {
+		opts = append(opts, apw_tracing.WithProfilerLabels(*b.profilerLabels))
+	}
+	return opts
+}
+
+// defaultScopeName returns the instrumentation scope used by the tracer and meter returned
+// from Build: the service name, or fallback when no service name is configured.
+func (b *OtelBuilder) defaultScopeName(fallback string) string {
+	if b.serviceName != "" {
+		return b.serviceName
+	}
+	return fallback
 }
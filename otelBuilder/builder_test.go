@@ -0,0 +1,99 @@
+package otelBuilder
+
+import (
+	"context"
+	"errors"
+	"runtime/pprof"
+	"strings"
+	"testing"
+
+	apw_logging "otel-library/logs"
+	apw_tracing "otel-library/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// endSpanWithLimits records a span with one long attribute and one event under limits.
+func endSpanWithLimits(t *testing.T, limits trace.SpanLimits) trace.ReadOnlySpan {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(rec), trace.WithRawSpanLimits(limits))
+	_, span := tp.Tracer("test").Start(context.Background(), "span")
+	span.SetAttributes(attribute.String("key", strings.Repeat("x", 200)))
+	span.AddEvent("event")
+	span.End()
+
+	ended := rec.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("got %d ended spans, want 1", len(ended))
+	}
+	return ended[0]
+}
+
+func TestWithSpanLimitsKeepsUnsetLimits(t *testing.T) {
+	b := NewOtelBuilder().WithSpanLimits(trace.SpanLimits{AttributeValueLengthLimit: 100})
+
+	want := trace.NewSpanLimits()
+	want.AttributeValueLengthLimit = 100
+	if b.spanLimits != want {
+		t.Fatalf("spanLimits = %+v, want %+v", b.spanLimits, want)
+	}
+
+	span := endSpanWithLimits(t, b.spanLimits)
+	if got := len(span.Attributes()); got != 1 {
+		t.Fatalf("got %d attributes, want 1", got)
+	}
+	if got := len(span.Attributes()[0].Value.AsString()); got != 100 {
+		t.Errorf("attribute length = %d, want 100", got)
+	}
+	if got := len(span.Events()); got != 1 {
+		t.Errorf("got %d events, want 1", got)
+	}
+}
+
+func TestWithMaxEventsOverridesSingleLimit(t *testing.T) {
+	b := NewOtelBuilder().WithMaxEvents(0)
+
+	span := endSpanWithLimits(t, b.spanLimits)
+	if got := len(span.Events()); got != 0 {
+		t.Errorf("got %d events, want 0", got)
+	}
+	if got := len(span.Attributes()); got != 1 {
+		t.Errorf("got %d attributes, want 1", got)
+	}
+}
+
+func TestWithAttributeValueLengthLimitIgnoresZero(t *testing.T) {
+	b := NewOtelBuilder().WithAttributeValueLengthLimit(50).WithAttributeValueLengthLimit(0)
+	if got := b.spanLimits.AttributeValueLengthLimit; got != 50 {
+		t.Errorf("AttributeValueLengthLimit = %d, want 50", got)
+	}
+}
+
+func TestWithBaggageLimitsAppliesToTracing(t *testing.T) {
+	b := NewOtelBuilder().WithBaggageLimits(1, 0)
+	tp := trace.NewTracerProvider()
+	tr := apw_tracing.NewTracing(tp.Tracer("test"), apw_logging.NewNoopLogging(), b.tracingOptions(tp)...)
+
+	ctx, err := tr.SetBaggage(context.Background(), "first", "1")
+	if err != nil {
+		t.Fatalf("SetBaggage(first) = %v", err)
+	}
+	if _, err := tr.SetBaggage(ctx, "second", "2"); !errors.Is(err, apw_tracing.ErrBaggageLimit) {
+		t.Fatalf("SetBaggage(second) = %v, want ErrBaggageLimit", err)
+	}
+}
+
+func TestWithProfilerLabelsAppliesToTracing(t *testing.T) {
+	b := NewOtelBuilder().WithProfilerLabels(apw_tracing.ProfilerLabels{SpanID: true})
+	tp := trace.NewTracerProvider()
+	tr := apw_tracing.NewTracing(tp.Tracer("test"), apw_logging.NewNoopLogging(), b.tracingOptions(tp)...)
+
+	ctx, span := tr.StartSpan(context.Background(), "work")
+	defer span.End()
+	if got, _ := pprof.Label(ctx, apw_tracing.ProfilerLabelSpanID); got != span.SpanContext().SpanID().String() {
+		t.Errorf("%s label = %q, want the span ID", apw_tracing.ProfilerLabelSpanID, got)
+	}
+}
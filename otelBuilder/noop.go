@@ -0,0 +1,39 @@
+package otelBuilder
+
+import (
+	"os"
+	"strings"
+
+	apw_logging "otel-library/logs"
+	apw_metrics "otel-library/metrics"
+	apw_tracing "otel-library/tracing"
+
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// sdkDisabledEnv is the standard environment variable used to turn the SDK off.
+const sdkDisabledEnv = "OTEL_SDK_DISABLED"
+
+// NewNoop returns an Otel whose tracing, metrics and logging are backed by no-op
+// implementations. It is safe to use in unit tests, local runs, or as a fallback
+// when Build fails.
+func NewNoop() *Otel {
+	l := apw_logging.NewNoopLogging()
+	return NewOtel(newNoopTracing(l), newNoopMetrics(), l)
+}
+
+func newNoopTracing(l apw_logging.OtelLogging) apw_tracing.OtelTracing {
+	provider := tracenoop.NewTracerProvider()
+	return apw_tracing.NewTracing(provider.Tracer(""), l, apw_tracing.WithTracerProvider(provider))
+}
+
+func newNoopMetrics() apw_metrics.OtelMetric {
+	provider := metricnoop.NewMeterProvider()
+	return apw_metrics.NewMetric(provider.Meter(""), apw_metrics.WithMeterProvider(provider))
+}
+
+// sdkDisabledByEnv reports whether OTEL_SDK_DISABLED is set to true.
+func sdkDisabledByEnv() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv(sdkDisabledEnv)), "true")
+}
@@ -0,0 +1,50 @@
+package otelBuilder
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBuildDisabledReturnsNoop(t *testing.T) {
+	tests := map[string]struct {
+		env     string
+		builder func() *OtelBuilder
+	}{
+		"WithEnabled(false)": {builder: func() *OtelBuilder { return NewOtelBuilder().WithEnabled(false) }},
+		"OTEL_SDK_DISABLED":  {env: "TRUE", builder: NewOtelBuilder},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(sdkDisabledEnv, tt.env)
+			// An unreachable endpoint shows that no exporter is created.
+			tracing, metrics, err := tt.builder().WithEndpointURL("http://127.0.0.1:1").Build(context.Background(), newRecordingLogging())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, span := tracing.StartSpan(context.Background(), "work")
+			if span.IsRecording() {
+				t.Error("span is recording, want a no-op span")
+			}
+			span.End()
+			counter, err := metrics.CreateCounter("calls")
+			if err != nil {
+				t.Fatal(err)
+			}
+			counter.Add(context.Background(), 1)
+		})
+	}
+}
+
+func TestNewNoopIsUsable(t *testing.T) {
+	o := NewNoop()
+	ctx, span := o.Tracer("scope", "v1").StartSpan(context.Background(), "work")
+	defer span.End()
+	if span.IsRecording() {
+		t.Error("span is recording, want a no-op span")
+	}
+	o.GetLogs().WithContext(ctx).Warnf("discarded %d", 1)
+	if _, err := o.Meter("scope", "v1").CreateHistogram("latency"); err != nil {
+		t.Fatal(err)
+	}
+}
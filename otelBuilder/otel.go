@@ -31,3 +31,15 @@ func (o *Otel) GetMetrics() apw_metrics.OtelMetric {
 func (o *Otel) GetLogs() apw_logging.OtelLogging {
 	return o.Logs
 }
+
+// Tracer returns an OtelTracing for the given instrumentation scope, backed by the same
+// TracerProvider as o.Tracing.
+func (o *Otel) Tracer(scopeName, version string) apw_tracing.OtelTracing {
+	return o.Tracing.Tracer(scopeName, version)
+}
+
+// Meter returns an OtelMetric for the given instrumentation scope, backed by the same
+// MeterProvider as o.Metrics.
+func (o *Otel) Meter(scopeName, version string) apw_metrics.OtelMetric {
+	return o.Metrics.Meter(scopeName, version)
+}
@@ -0,0 +1,64 @@
+package otelBuilder
+
+import (
+	"context"
+	"testing"
+
+	apw_logging "otel-library/logs"
+	apw_metrics "otel-library/metrics"
+	apw_tracing "otel-library/tracing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestScopedTracerAndMeterRecordTheirScope(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	tp := trace.NewTracerProvider(trace.WithSpanProcessor(spans))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	l := apw_logging.NewNoopLogging()
+	o := NewOtel(
+		apw_tracing.NewTracing(tp.Tracer("default"), l, apw_tracing.WithTracerProvider(tp)),
+		apw_metrics.NewMetric(mp.Meter("default"), apw_metrics.WithMeterProvider(mp)),
+		l,
+	)
+
+	_, span := o.Tracer("orders", "v1.2.0").StartSpan(context.Background(), "work")
+	span.End()
+	_, span = o.GetTracing().StartSpan(context.Background(), "default work")
+	span.End()
+
+	ended := spans.Ended()
+	if scope := ended[0].InstrumentationScope(); scope.Name != "orders" || scope.Version != "v1.2.0" {
+		t.Errorf("scoped span scope = %+v, want orders v1.2.0", scope)
+	}
+	if scope := ended[1].InstrumentationScope(); scope.Name != "default" {
+		t.Errorf("default span scope = %+v, want the unscoped tracer", scope)
+	}
+
+	counter, err := o.Meter("orders", "v1.2.0").CreateCounter("orders.created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Add(context.Background(), 1)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	if len(rm.ScopeMetrics) != 1 || rm.ScopeMetrics[0].Scope.Name != "orders" || rm.ScopeMetrics[0].Scope.Version != "v1.2.0" {
+		t.Errorf("scope metrics = %+v, want orders.created under orders v1.2.0", rm.ScopeMetrics)
+	}
+}
+
+func TestDefaultScopeName(t *testing.T) {
+	if got := NewOtelBuilder().defaultScopeName(tracerName); got != tracerName {
+		t.Errorf("defaultScopeName without a service = %q, want %q", got, tracerName)
+	}
+	if got := NewOtelBuilder().WithServiceName("checkout").defaultScopeName(tracerName); got != "checkout" {
+		t.Errorf("defaultScopeName = %q, want the service name", got)
+	}
+}
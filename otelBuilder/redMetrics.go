@@ -0,0 +1,114 @@
+package otelBuilder
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redMeterName is the instrumentation scope of the metrics derived from spans.
+const redMeterName = "otel-library/red"
+
+// exemplarEnv is the environment variable enabling exemplars in the metric SDK, which has no
+// programmatic option for them yet.
+const exemplarEnv = "OTEL_GO_X_EXEMPLAR"
+
+// Attribute keys identifying the span a RED measurement was derived from.
+const (
+	spanNameKey   = attribute.Key("span.name")
+	spanKindKey   = attribute.Key("span.kind")
+	statusCodeKey = attribute.Key("status.code")
+)
+
+// redMetricsProcessor derives request count, error count and duration metrics from ended spans.
+type redMetricsProcessor struct {
+	dimensions []attribute.Key
+	calls      metric.Int64Counter
+	errors     metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+func newREDMetricsProcessor(meter metric.Meter, dimensions []string) (*redMetricsProcessor, error) {
+	p := &redMetricsProcessor{}
+	for _, d := range dimensions {
+		p.dimensions = append(p.dimensions, attribute.Key(d))
+	}
+
+	var err error
+	p.calls, err = meter.Int64Counter("span.calls",
+		metric.WithDescription("Number of ended spans"),
+		metric.WithUnit("{span}"))
+	if err != nil {
+		return nil, err
+	}
+	p.errors, err = meter.Int64Counter("span.errors",
+		metric.WithDescription("Number of ended spans with an error status"),
+		metric.WithUnit("{span}"))
+	if err != nil {
+		return nil, err
+	}
+	p.duration, err = meter.Float64Histogram("span.duration",
+		metric.WithDescription("Duration of ended spans"),
+		metric.WithUnit("ms"))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *redMetricsProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd records the span. The measurements are made in a context holding the span, so that
+// exemplars, when enabled, point back at it.
+func (p *redMetricsProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	ctx := trace.ContextWithSpanContext(context.Background(), s.SpanContext())
+	attrs := metric.WithAttributes(p.attributes(s)...)
+
+	p.calls.Add(ctx, 1, attrs)
+	if s.Status().Code == codes.Error {
+		p.errors.Add(ctx, 1, attrs)
+	}
+	p.duration.Record(ctx, float64(s.EndTime().Sub(s.StartTime()))/1e6, attrs)
+}
+
+func (p *redMetricsProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func (p *redMetricsProcessor) ForceFlush(context.Context) error {
+	return nil
+}
+
+// attributes returns the span name, kind and status plus the configured dimensions present on s.
+func (p *redMetricsProcessor) attributes(s sdktrace.ReadOnlySpan) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, 3+len(p.dimensions))
+	attrs = append(attrs,
+		spanNameKey.String(s.Name()),
+		spanKindKey.String(s.SpanKind().String()),
+		statusCodeKey.String(s.Status().Code.String()),
+	)
+	if len(p.dimensions) == 0 {
+		return attrs
+	}
+	for _, attr := range s.Attributes() {
+		for _, d := range p.dimensions {
+			if attr.Key == d {
+				attrs = append(attrs, attr)
+				break
+			}
+		}
+	}
+	return attrs
+}
+
+// exemplarsEnabled reports whether OTEL_GO_X_EXEMPLAR is set to true, the same check the SDK
+// makes before recording exemplars.
+func exemplarsEnabled() bool {
+	return strings.EqualFold(os.Getenv(exemplarEnv), "true")
+}
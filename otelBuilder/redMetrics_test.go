@@ -0,0 +1,174 @@
+package otelBuilder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	apw_logging "otel-library/logs"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// recordingLogging keeps the warnings logged through it.
+type recordingLogging struct {
+	apw_logging.OtelLogging
+	mu       sync.Mutex
+	warnings []string
+}
+
+func newRecordingLogging() *recordingLogging {
+	return &recordingLogging{OtelLogging: apw_logging.NewNoopLogging()}
+}
+
+func (l *recordingLogging) Warnf(template string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warnings = append(l.warnings, fmt.Sprintf(template, args...))
+}
+
+func (l *recordingLogging) WithContext(context.Context) apw_logging.OtelLogging {
+	return l
+}
+
+func (l *recordingLogging) Warnings() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.warnings...)
+}
+
+// newREDTestProviders returns a TracerProvider deriving RED metrics into reader.
+func newREDTestProviders(t *testing.T, dimensions ...string) (*sdktrace.TracerProvider, *sdkmetric.ManualReader) {
+	t.Helper()
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	processor, err := newREDMetricsProcessor(mp.Meter(redMeterName), dimensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor)), reader
+}
+
+func collectMetrics(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			found[m.Name] = m.Data
+		}
+	}
+	return found
+}
+
+func TestREDMetricsCountCallsErrorsAndDuration(t *testing.T) {
+	tp, reader := newREDTestProviders(t, "http.route")
+	tracer := tp.Tracer("test")
+	route := attribute.String("http.route", "/orders/{id}")
+
+	for _, failed := range []bool{false, false, true} {
+		_, span := tracer.Start(context.Background(), "GET /orders/{id}")
+		span.SetAttributes(route, attribute.String("user.id", "42"))
+		if failed {
+			span.SetStatus(codes.Error, "boom")
+		}
+		span.End()
+	}
+
+	found := collectMetrics(t, reader)
+	var calls int64
+	for _, point := range found["span.calls"].(metricdata.Sum[int64]).DataPoints {
+		calls += point.Value
+		if v, ok := point.Attributes.Value("http.route"); !ok || v.AsString() != "/orders/{id}" {
+			t.Errorf("span.calls attributes %v lack the http.route dimension", point.Attributes.ToSlice())
+		}
+		if point.Attributes.HasValue("user.id") {
+			t.Errorf("span.calls attributes %v include user.id, which is not a dimension", point.Attributes.ToSlice())
+		}
+		if v, _ := point.Attributes.Value(spanNameKey); v.AsString() != "GET /orders/{id}" {
+			t.Errorf("%s = %q", spanNameKey, v.AsString())
+		}
+	}
+	if calls != 3 {
+		t.Errorf("span.calls = %d, want 3", calls)
+	}
+
+	errorPoints := found["span.errors"].(metricdata.Sum[int64]).DataPoints
+	if len(errorPoints) != 1 || errorPoints[0].Value != 1 {
+		t.Fatalf("span.errors points = %v, want a single count of 1", errorPoints)
+	}
+	if v, _ := errorPoints[0].Attributes.Value(statusCodeKey); v.AsString() != codes.Error.String() {
+		t.Errorf("%s = %q, want %q", statusCodeKey, v.AsString(), codes.Error.String())
+	}
+
+	var durations uint64
+	for _, point := range found["span.duration"].(metricdata.Histogram[float64]).DataPoints {
+		durations += point.Count
+	}
+	if durations != 3 {
+		t.Errorf("span.duration count = %d, want 3", durations)
+	}
+}
+
+func TestREDMetricsExemplarsPointAtSpans(t *testing.T) {
+	t.Setenv(exemplarEnv, "true")
+	tp, reader := newREDTestProviders(t)
+
+	_, span := tp.Tracer("test").Start(context.Background(), "work")
+	span.End()
+
+	points := collectMetrics(t, reader)["span.duration"].(metricdata.Histogram[float64]).DataPoints
+	if len(points) != 1 || len(points[0].Exemplars) != 1 {
+		t.Fatalf("span.duration points = %+v, want one exemplar", points)
+	}
+	exemplar := points[0].Exemplars[0]
+	if want := span.SpanContext().TraceID(); string(exemplar.TraceID) != string(want[:]) {
+		t.Errorf("exemplar trace ID = %x, want %s", exemplar.TraceID, want)
+	}
+	if want := span.SpanContext().SpanID(); string(exemplar.SpanID) != string(want[:]) {
+		t.Errorf("exemplar span ID = %x, want %s", exemplar.SpanID, want)
+	}
+}
+
+func TestREDMetricsHaveNoExemplarsByDefault(t *testing.T) {
+	t.Setenv(exemplarEnv, "")
+	tp, reader := newREDTestProviders(t)
+
+	_, span := tp.Tracer("test").Start(context.Background(), "work")
+	span.End()
+
+	for _, point := range collectMetrics(t, reader)["span.duration"].(metricdata.Histogram[float64]).DataPoints {
+		if len(point.Exemplars) != 0 {
+			t.Errorf("span.duration exemplars = %+v, want none without %s", point.Exemplars, exemplarEnv)
+		}
+	}
+}
+
+func TestBuildWarnsWhenREDMetricsLackExemplars(t *testing.T) {
+	for _, enabled := range []string{"", "true"} {
+		t.Run("OTEL_GO_X_EXEMPLAR="+enabled, func(t *testing.T) {
+			t.Setenv(exemplarEnv, enabled)
+			l := newRecordingLogging()
+			if _, _, err := NewOtelBuilder().WithConsoleExporter().WithREDMetrics().Build(context.Background(), l); err != nil {
+				t.Fatal(err)
+			}
+
+			warned := false
+			for _, warning := range l.Warnings() {
+				warned = warned || strings.Contains(warning, exemplarEnv)
+			}
+			if warned != (enabled == "") {
+				t.Errorf("warnings = %v, want a %s warning only when it is unset", l.Warnings(), exemplarEnv)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package otelBuilder
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"otel-library/internal/goroutine"
+	apw_logging "otel-library/logs"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultSlowCheckInterval is how often running spans are checked when no interval is set.
+const defaultSlowCheckInterval = 100 * time.Millisecond
+
+// defaultStackInterval is the minimum time between two goroutine stack captures for
+// CaptureStack when no interval is set.
+const defaultStackInterval = time.Second
+
+// slowOperationEventName is the span event added to spans exceeding their threshold.
+const slowOperationEventName = "slow_operation"
+
+// SlowOperationConfig configures the slow-operation detector enabled with
+// OtelBuilder.WithSlowOperationDetector.
+type SlowOperationConfig struct {
+	// DefaultThreshold applies to span names without an entry in Thresholds. Zero only
+	// watches the named spans.
+	DefaultThreshold time.Duration
+	// Thresholds sets the threshold of individual span names.
+	Thresholds map[string]time.Duration
+	// CaptureStack adds the stack of the goroutine that started the span to the warning and
+	// span event. Getting it means capturing the stacks of all goroutines, which stops the
+	// world, so captures are limited by StackInterval; spans reported in between have no stack.
+	CaptureStack bool
+	// StackInterval is the minimum time between two stack captures for CaptureStack. Zero uses
+	// one second.
+	StackInterval time.Duration
+	// DumpAllThreshold logs the stacks of all goroutines once a span has been running this
+	// long. Zero disables the dump.
+	DumpAllThreshold time.Duration
+	// CheckInterval is how often running spans are checked. Zero uses 100ms.
+	CheckInterval time.Duration
+}
+
+// threshold returns the threshold of name, or zero when it is not watched.
+func (c SlowOperationConfig) threshold(name string) time.Duration {
+	if t, ok := c.Thresholds[name]; ok {
+		return t
+	}
+	return c.DefaultThreshold
+}
+
+// slowOperationDetector is a span processor that periodically checks running spans and reports
+// the ones exceeding their threshold.
+type slowOperationDetector struct {
+	cfg SlowOperationConfig
+	l   apw_logging.OtelLogging
+
+	mu      sync.Mutex
+	running map[trace.SpanID]*watchedSpan
+
+	// lastStacks is when goroutine stacks were last captured. It is only used by check.
+	lastStacks time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// watchedSpan is a running span with a threshold.
+type watchedSpan struct {
+	span      sdktrace.ReadWriteSpan
+	threshold time.Duration
+	goroutine uint64
+	warned    bool
+	dumped    bool
+}
+
+func newSlowOperationDetector(cfg SlowOperationConfig, l apw_logging.OtelLogging) *slowOperationDetector {
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = defaultSlowCheckInterval
+	}
+	if cfg.StackInterval <= 0 {
+		cfg.StackInterval = defaultStackInterval
+	}
+	d := &slowOperationDetector{
+		cfg:     cfg,
+		l:       l,
+		running: make(map[trace.SpanID]*watchedSpan),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go d.loop()
+	return d
+}
+
+// OnStart watches s when its name has a threshold. It runs on the goroutine starting the span,
+// which is the goroutine whose stack CaptureStack reports.
+func (d *slowOperationDetector) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	threshold := d.cfg.threshold(s.Name())
+	if threshold <= 0 && d.cfg.DumpAllThreshold <= 0 {
+		return
+	}
+
+	w := &watchedSpan{span: s, threshold: threshold}
+	if d.cfg.CaptureStack {
+		w.goroutine = goroutine.ID()
+	}
+	d.mu.Lock()
+	d.running[s.SpanContext().SpanID()] = w
+	d.mu.Unlock()
+}
+
+func (d *slowOperationDetector) OnEnd(s sdktrace.ReadOnlySpan) {
+	d.mu.Lock()
+	delete(d.running, s.SpanContext().SpanID())
+	d.mu.Unlock()
+}
+
+// Shutdown stops the background check.
+func (d *slowOperationDetector) Shutdown(ctx context.Context) error {
+	d.stopOnce.Do(func() { close(d.stop) })
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *slowOperationDetector) ForceFlush(context.Context) error {
+	return nil
+}
+
+func (d *slowOperationDetector) loop() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.check(time.Now())
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// check reports every watched span that crossed its threshold since the previous check.
+func (d *slowOperationDetector) check(now time.Time) {
+	var slow, dump []*watchedSpan
+	d.mu.Lock()
+	for _, w := range d.running {
+		elapsed := now.Sub(w.span.StartTime())
+		if w.threshold > 0 && !w.warned && elapsed >= w.threshold {
+			w.warned = true
+			slow = append(slow, w)
+		}
+		if d.cfg.DumpAllThreshold > 0 && !w.dumped && elapsed >= d.cfg.DumpAllThreshold {
+			w.dumped = true
+			dump = append(dump, w)
+		}
+	}
+	d.mu.Unlock()
+
+	if len(slow) == 0 && len(dump) == 0 {
+		return
+	}
+
+	var stacks []byte
+	if len(dump) > 0 || (d.cfg.CaptureStack && len(slow) > 0 && now.Sub(d.lastStacks) >= d.cfg.StackInterval) {
+		stacks = allGoroutineStacks()
+		d.lastStacks = now
+	}
+	for _, w := range slow {
+		d.reportSlow(w, now.Sub(w.span.StartTime()), stacks)
+	}
+	if len(dump) > 0 {
+		// One dump covers every span that crossed DumpAllThreshold in this check; it is logged
+		// in the context of the longest running one.
+		oldest := dump[0]
+		for _, w := range dump[1:] {
+			if w.span.StartTime().Before(oldest.span.StartTime()) {
+				oldest = w
+			}
+		}
+		ctx := trace.ContextWithSpanContext(context.Background(), oldest.span.SpanContext())
+		d.l.WithContext(ctx).Warnf("%d operation(s) running for over %s, longest %q for %s, dumping all goroutines:\n%s",
+			len(dump), d.cfg.DumpAllThreshold, oldest.span.Name(), now.Sub(oldest.span.StartTime()), stacks)
+	}
+}
+
+// reportSlow logs a warning with the span's trace IDs and adds a slow_operation event to it.
+func (d *slowOperationDetector) reportSlow(w *watchedSpan, elapsed time.Duration, stacks []byte) {
+	attrs := []attribute.KeyValue{
+		attribute.Float64("slow.threshold", w.threshold.Seconds()),
+		attribute.Float64("slow.elapsed", elapsed.Seconds()),
+	}
+	var stack string
+	if w.goroutine != 0 {
+		stack = goroutineStack(stacks, w.goroutine)
+		if stack != "" {
+			attrs = append(attrs, attribute.String("slow.stack", stack))
+		}
+	}
+	w.span.AddEvent(slowOperationEventName, trace.WithAttributes(attrs...))
+
+	ctx := trace.ContextWithSpanContext(context.Background(), w.span.SpanContext())
+	if stack != "" {
+		d.l.WithContext(ctx).Warnf("slow operation %q running for %s (threshold %s):\n%s", w.span.Name(), elapsed, w.threshold, stack)
+		return
+	}
+	d.l.WithContext(ctx).Warnf("slow operation %q running for %s (threshold %s)", w.span.Name(), elapsed, w.threshold)
+}
+
+// allGoroutineStacks returns the stacks of all goroutines, growing the buffer until they fit.
+func allGoroutineStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineStack extracts the stack of goroutine id from a dump of all goroutines.
+func goroutineStack(stacks []byte, id uint64) string {
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " ")
+	for _, block := range bytes.Split(stacks, []byte("\n\n")) {
+		if bytes.HasPrefix(block, header) {
+			return string(block)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,191 @@
+package otelBuilder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apw_metrics "otel-library/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestDetector returns a detector whose background check never runs during the test, so
+// that the test drives check itself, and a tracer whose spans it watches.
+func newTestDetector(t *testing.T, cfg SlowOperationConfig) (*slowOperationDetector, trace.Tracer, *tracetest.SpanRecorder, *recordingLogging) {
+	t.Helper()
+	cfg.CheckInterval = time.Hour
+	l := newRecordingLogging()
+	d := newSlowOperationDetector(cfg, l)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(d), sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return d, tp.Tracer("test"), recorder, l
+}
+
+// slowEvent returns the slow_operation event of span, if any.
+func slowEvent(span sdktrace.ReadOnlySpan) (sdktrace.Event, bool) {
+	for _, event := range span.Events() {
+		if event.Name == slowOperationEventName {
+			return event, true
+		}
+	}
+	return sdktrace.Event{}, false
+}
+
+func hasAttribute(attrs []attribute.KeyValue, key attribute.Key) bool {
+	for _, attr := range attrs {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSlowOperationReportedOnceAfterThreshold(t *testing.T) {
+	d, tracer, recorder, l := newTestDetector(t, SlowOperationConfig{
+		DefaultThreshold: time.Second,
+		Thresholds:       map[string]time.Duration{"fast": time.Hour},
+	})
+	_, slow := tracer.Start(context.Background(), "query")
+	_, fast := tracer.Start(context.Background(), "fast")
+	start := slow.(sdktrace.ReadOnlySpan).StartTime()
+
+	d.check(start.Add(500 * time.Millisecond))
+	if len(l.Warnings()) != 0 {
+		t.Fatalf("warnings before the threshold: %v", l.Warnings())
+	}
+	d.check(start.Add(2 * time.Second))
+	d.check(start.Add(3 * time.Second))
+	slow.End()
+	fast.End()
+
+	if warnings := l.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], `slow operation "query"`) {
+		t.Errorf("warnings = %v, want one for query", warnings)
+	}
+	for _, span := range recorder.Ended() {
+		_, ok := slowEvent(span)
+		if ok != (span.Name() == "query") {
+			t.Errorf("span %s has slow_operation event = %v", span.Name(), ok)
+		}
+	}
+}
+
+func TestSlowOperationStackCapturesAreRateLimited(t *testing.T) {
+	d, tracer, recorder, _ := newTestDetector(t, SlowOperationConfig{
+		DefaultThreshold: time.Second,
+		CaptureStack:     true,
+		StackInterval:    time.Minute,
+	})
+	_, first := tracer.Start(context.Background(), "first")
+	start := first.(sdktrace.ReadOnlySpan).StartTime()
+	d.check(start.Add(2 * time.Second))
+
+	_, second := tracer.Start(context.Background(), "second")
+	d.check(start.Add(4 * time.Second))
+
+	_, third := tracer.Start(context.Background(), "third")
+	d.check(start.Add(2 * time.Minute))
+	first.End()
+	second.End()
+	third.End()
+
+	want := map[string]bool{"first": true, "second": false, "third": true}
+	for _, span := range recorder.Ended() {
+		event, ok := slowEvent(span)
+		if !ok {
+			t.Fatalf("span %s was not reported", span.Name())
+		}
+		if got := hasAttribute(event.Attributes, "slow.stack"); got != want[span.Name()] {
+			t.Errorf("span %s has slow.stack = %v, want %v", span.Name(), got, want[span.Name()])
+		}
+	}
+}
+
+func TestSlowOperationStackIsTheStartingGoroutine(t *testing.T) {
+	d, tracer, recorder, _ := newTestDetector(t, SlowOperationConfig{DefaultThreshold: time.Second, CaptureStack: true})
+	_, span := tracer.Start(context.Background(), "query")
+	d.check(span.(sdktrace.ReadOnlySpan).StartTime().Add(2 * time.Second))
+	span.End()
+
+	event, _ := slowEvent(recorder.Ended()[0])
+	for _, attr := range event.Attributes {
+		if attr.Key == "slow.stack" && !strings.Contains(attr.Value.AsString(), "TestSlowOperationStackIsTheStartingGoroutine") {
+			t.Errorf("slow.stack = %q, want the test goroutine", attr.Value.AsString())
+		}
+	}
+}
+
+func TestSlowOperationDumpsAllGoroutinesOnce(t *testing.T) {
+	d, tracer, _, l := newTestDetector(t, SlowOperationConfig{DumpAllThreshold: time.Minute})
+	_, span := tracer.Start(context.Background(), "stuck")
+	defer span.End()
+	start := span.(sdktrace.ReadOnlySpan).StartTime()
+
+	d.check(start.Add(time.Second))
+	d.check(start.Add(2 * time.Minute))
+	d.check(start.Add(3 * time.Minute))
+
+	warnings := l.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "dumping all goroutines") || !strings.Contains(warnings[0], "goroutine ") {
+		t.Errorf("warnings = %v, want a single goroutine dump", warnings)
+	}
+}
+
+func TestSlowOperationDetectorReportsInBackground(t *testing.T) {
+	l := newRecordingLogging()
+	d := newSlowOperationDetector(SlowOperationConfig{DefaultThreshold: 10 * time.Millisecond, CheckInterval: 5 * time.Millisecond}, l)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(d))
+	defer tp.Shutdown(context.Background())
+
+	_, span := tp.Tracer("test").Start(context.Background(), "query")
+	defer span.End()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(l.Warnings()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no warning from the background check")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// goroutinesRunning counts the goroutines whose stack contains function. Goroutines only show
+// up in stack dumps once they are scheduled, so recently started ones are given time to start.
+func goroutinesRunning(function string) int {
+	time.Sleep(20 * time.Millisecond)
+	return bytes.Count(allGoroutineStacks(), []byte(function))
+}
+
+func TestBuildShutsDownProvidersOnError(t *testing.T) {
+	errRuntime := errors.New("runtime metrics unavailable")
+	startRuntimeMetrics = func(metric.Meter, time.Duration) error { return errRuntime }
+	t.Cleanup(func() { startRuntimeMetrics = apw_metrics.StartRuntimeMetrics })
+
+	functions := []string{"(*slowOperationDetector).loop", "(*batchSpanProcessor).processQueue", "(*PeriodicReader).run"}
+	before := make(map[string]int, len(functions))
+	for _, function := range functions {
+		before[function] = goroutinesRunning(function)
+	}
+
+	_, _, err := NewOtelBuilder().
+		WithConsoleExporter().
+		WithRuntimeMetrics().
+		WithSlowOperationDetector(SlowOperationConfig{DefaultThreshold: time.Second}).
+		Build(context.Background(), newRecordingLogging())
+	if !errors.Is(err, errRuntime) {
+		t.Fatalf("Build returned %v, want %v", err, errRuntime)
+	}
+
+	for _, function := range functions {
+		if got := goroutinesRunning(function); got > before[function] {
+			t.Errorf("%d %s goroutine(s) still running after Build failed", got-before[function], function)
+		}
+	}
+}
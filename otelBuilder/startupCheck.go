@@ -0,0 +1,253 @@
+package otelBuilder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	apw_logging "otel-library/logs"
+)
+
+const (
+	defaultStartupCheckTimeout = time.Second * 5
+	defaultOTLPEndpoint        = "localhost:4318"
+	defaultTracesPath          = "/v1/traces"
+)
+
+// StartupPolicy decides what Build does when the startup connectivity check fails.
+type StartupPolicy int
+
+const (
+	// StartupPolicyWarn logs a warning and keeps the OTLP exporters.
+	StartupPolicyWarn StartupPolicy = iota
+	// StartupPolicyFail makes Build return the check error.
+	StartupPolicyFail
+	// StartupPolicyConsole logs a warning and falls back to the console exporters.
+	StartupPolicyConsole
+)
+
+// StartupCheckFailure classifies why the startup connectivity check failed.
+type StartupCheckFailure string
+
+const (
+	StartupFailureDNS        StartupCheckFailure = "dns"
+	StartupFailureConnection StartupCheckFailure = "connection"
+	StartupFailureAuth       StartupCheckFailure = "auth"
+	StartupFailureStatus     StartupCheckFailure = "status"
+)
+
+// StartupCheckError is returned, or logged, when the OTLP endpoint could not be reached
+// or rejected the probe request.
+type StartupCheckError struct {
+	URL        string
+	Failure    StartupCheckFailure
+	StatusCode int
+	Err        error
+}
+
+// Error implements the error interface for StartupCheckError
+func (e *StartupCheckError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("OTLP endpoint %s check failed (%s): HTTP %d %s", e.URL, e.Failure, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("OTLP endpoint %s check failed (%s): %v", e.URL, e.Failure, e.Err)
+}
+
+func (e *StartupCheckError) Unwrap() error {
+	return e.Err
+}
+
+type startupCheck struct {
+	policy  StartupPolicy
+	timeout time.Duration
+}
+
+// WithStartupCheck enables a connectivity probe during Build. The probe sends an empty OTLP
+// trace export request to the exporter's URL, with the configured and OTEL_EXPORTER_OTLP_*
+// headers and TLS settings, and applies the given policy when the endpoint is unreachable or
+// rejects the request. A zero timeout uses the default.
+func (b *OtelBuilder) WithStartupCheck(policy StartupPolicy, timeout time.Duration) *OtelBuilder {
+	if timeout <= 0 {
+		timeout = defaultStartupCheckTimeout
+	}
+	b.startupCheck = &startupCheck{policy: policy, timeout: timeout}
+	return b
+}
+
+// runStartupCheck probes the endpoint and reports whether Build should fall back to the
+// console exporters. An error is returned only under StartupPolicyFail.
+func (b *OtelBuilder) runStartupCheck(ctx context.Context, l apw_logging.OtelLogging) (bool, error) {
+	statusCode, err := b.probeEndpoint(ctx)
+	if err == nil {
+		l.Infof("OTLP endpoint %s reachable: HTTP %d", b.tracesURL(), statusCode)
+		return false, nil
+	}
+
+	switch b.startupCheck.policy {
+	case StartupPolicyFail:
+		return false, fmt.Errorf("startup check failed: %w", err)
+	case StartupPolicyConsole:
+		l.Warnf("%v; falling back to the console exporter", err)
+		return true, nil
+	default:
+		l.Warnf("%v; continuing with the OTLP exporter", err)
+		return false, nil
+	}
+}
+
+// probeEndpoint sends an empty export request to the traces endpoint and returns the HTTP status.
+func (b *OtelBuilder) probeEndpoint(ctx context.Context) (int, error) {
+	target := b.tracesURL()
+
+	ctx, cancel := context.WithTimeout(ctx, b.startupCheck.timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(nil))
+	if err != nil {
+		return 0, &StartupCheckError{URL: target, Failure: StartupFailureConnection, Err: err}
+	}
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range b.probeHeaders() {
+		request.Header.Set(key, value)
+	}
+
+	// Like the exporter, start from the default transport so proxy settings are honoured.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if b.tlsConfig != nil {
+		transport.TLSClientConfig = b.tlsConfig
+	}
+	client := &http.Client{
+		Timeout:   b.startupCheck.timeout,
+		Transport: transport,
+	}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Do(request)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			return 0, &StartupCheckError{URL: target, Failure: StartupFailureDNS, Err: err}
+		}
+		return 0, &StartupCheckError{URL: target, Failure: StartupFailureConnection, Err: err}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return resp.StatusCode, &StartupCheckError{URL: target, Failure: StartupFailureAuth, StatusCode: resp.StatusCode}
+	case resp.StatusCode >= 300:
+		return resp.StatusCode, &StartupCheckError{URL: target, Failure: StartupFailureStatus, StatusCode: resp.StatusCode}
+	}
+	return resp.StatusCode, nil
+}
+
+// tracesURL resolves the URL the OTLP trace exporter posts to. Like otlptracehttp, it starts
+// from the defaults, applies the OTEL_EXPORTER_OTLP_* environment variables and then the
+// builder's endpoint URL and insecure flag, and cleans the path the same way.
+func (b *OtelBuilder) tracesURL() string {
+	host, urlPath, insecure := defaultOTLPEndpoint, defaultTracesPath, false
+	if u, ok := envURL("OTEL_EXPORTER_OTLP_ENDPOINT"); ok {
+		// The generic endpoint is a base URL the signal path is appended to.
+		host, urlPath, insecure = u.Host, path.Join(u.Path, defaultTracesPath), insecureScheme(u.Scheme)
+	}
+	if u, ok := envURL("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); ok {
+		// Per-signal endpoints are used as is, with the root path when they have none.
+		host, urlPath, insecure = u.Host, u.Path, insecureScheme(u.Scheme)
+		if urlPath == "" {
+			urlPath = "/"
+		}
+	}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_INSECURE", "OTEL_EXPORTER_OTLP_TRACES_INSECURE"} {
+		if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+			insecure = strings.EqualFold(v, "true")
+		}
+	}
+	if b.endpointURL != "" {
+		if u, err := url.Parse(b.endpointURL); err == nil {
+			host, urlPath = u.Host, u.Path
+			insecure = insecure || u.Scheme != "https"
+		}
+	}
+	insecure = insecure || b.insecure
+
+	scheme := "https"
+	if insecure {
+		scheme = "http"
+	}
+	return (&url.URL{Scheme: scheme, Host: host, Path: cleanTracesPath(urlPath)}).String()
+}
+
+// probeHeaders returns the headers the exporter sends: OTEL_EXPORTER_OTLP_HEADERS, then
+// OTEL_EXPORTER_OTLP_TRACES_HEADERS, then the headers set with WithHeaders, later ones
+// winning for the same key.
+func (b *OtelBuilder) probeHeaders() map[string]string {
+	headers := map[string]string{}
+	for _, name := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		for key, value := range envHeaders(name) {
+			headers[key] = value
+		}
+	}
+	for key, value := range b.headers {
+		headers[key] = value
+	}
+	return headers
+}
+
+// envURL parses the URL in the environment variable name, if it is set.
+func envURL(name string) (*url.URL, bool) {
+	v := strings.TrimSpace(os.Getenv(name))
+	if v == "" {
+		return nil, false
+	}
+	u, err := url.Parse(v)
+	return u, err == nil
+}
+
+// envHeaders parses the comma-separated key=value list in the environment variable name,
+// percent-decoding the values and skipping malformed entries.
+func envHeaders(name string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv(name), ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		decoded, err := url.PathUnescape(value)
+		if err != nil {
+			continue
+		}
+		headers[key] = strings.TrimSpace(decoded)
+	}
+	return headers
+}
+
+// insecureScheme reports whether an endpoint with the given scheme is reached without TLS.
+func insecureScheme(scheme string) bool {
+	switch strings.ToLower(scheme) {
+	case "http", "unix":
+		return true
+	}
+	return false
+}
+
+// cleanTracesPath cleans urlPath, making it absolute, and falls back to the default traces
+// path when it is empty.
+func cleanTracesPath(urlPath string) string {
+	cleaned := path.Clean(strings.TrimSpace(urlPath))
+	if cleaned == "." {
+		return defaultTracesPath
+	}
+	if !path.IsAbs(cleaned) {
+		cleaned = "/" + cleaned
+	}
+	return cleaned
+}
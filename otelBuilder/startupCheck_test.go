@@ -0,0 +1,217 @@
+package otelBuilder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracesURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		insecure bool
+		env      map[string]string
+		want     string
+	}{
+		{name: "default", want: "https://localhost:4318/v1/traces"},
+		{name: "default insecure", insecure: true, want: "http://localhost:4318/v1/traces"},
+		{name: "builder endpoint", endpoint: "http://collector:4318", want: "http://collector:4318/v1/traces"},
+		{name: "builder endpoint with path", endpoint: "https://collector/otlp/traces", want: "https://collector/otlp/traces"},
+		{
+			name:     "builder endpoint wins over env",
+			endpoint: "http://builder:4318",
+			env:      map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://env:4318"},
+			want:     "http://builder:4318/v1/traces",
+		},
+		{
+			name: "generic env endpoint is a base URL",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://env:4318/base"},
+			want: "http://env:4318/base/v1/traces",
+		},
+		{
+			name: "signal env endpoint is used as is",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://env:4318",
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces:4318/custom",
+			},
+			want: "http://traces:4318/custom",
+		},
+		{
+			name: "env insecure",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_INSECURE": "true"},
+			want: "http://localhost:4318/v1/traces",
+		},
+		{name: "builder endpoint with root path", endpoint: "http://collector:4318/", want: "http://collector:4318/"},
+		{name: "builder endpoint with a non-https scheme", endpoint: "ftp://collector:4318", want: "http://collector:4318/v1/traces"},
+		{
+			name: "signal env endpoint without path uses root",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://traces:4318"},
+			want: "http://traces:4318/",
+		},
+		{
+			name: "generic env endpoint with root path",
+			env:  map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://env:4318/"},
+			want: "http://env:4318/v1/traces",
+		},
+		{
+			name: "https env endpoint with insecure override",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT":        "https://env:4318",
+				"OTEL_EXPORTER_OTLP_TRACES_INSECURE": "true",
+			},
+			want: "http://env:4318/v1/traces",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearOTLPEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			b := NewOtelBuilder().WithEndpointURL(tt.endpoint).WithInsecure(tt.insecure)
+			if got := b.tracesURL(); got != tt.want {
+				t.Errorf("tracesURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// clearOTLPEnv unsets the OTLP exporter environment variables for the duration of the test.
+func clearOTLPEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_INSECURE", "OTEL_EXPORTER_OTLP_TRACES_INSECURE",
+		"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestProbeHeadersMergeEnvAndBuilderHeaders(t *testing.T) {
+	clearOTLPEnv(t)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "api-key=generic,tenant=acme%20corp, bad")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_HEADERS", "api-key=traces")
+
+	got := NewOtelBuilder().WithHeaders(Header{"X-Builder": "yes"}).probeHeaders()
+	want := map[string]string{"api-key": "traces", "tenant": "acme corp", "X-Builder": "yes"}
+	if len(got) != len(want) {
+		t.Fatalf("probeHeaders() = %v, want %v", got, want)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("probeHeaders()[%q] = %q, want %q", key, got[key], value)
+		}
+	}
+}
+
+// TestProbeMatchesExporterRequest checks that the probe is sent to the same path, with the same
+// headers, as a real export.
+func TestProbeMatchesExporterRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		env      map[string]string
+		endpoint bool
+	}{
+		{name: "builder endpoint with root path", path: "/", endpoint: true},
+		{name: "builder endpoint without path", endpoint: true},
+		{name: "builder endpoint with custom path", path: "/otlp/traces", endpoint: true},
+		{name: "generic env endpoint", env: map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "{server}/base"}},
+		{name: "signal env endpoint without path", env: map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "{server}"}},
+		{
+			name: "env headers",
+			env: map[string]string{
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "{server}/v1/traces",
+				"OTEL_EXPORTER_OTLP_HEADERS":         "authorization=Bearer%20token",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []*http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			clearOTLPEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, strings.ReplaceAll(value, "{server}", server.URL))
+			}
+			b := NewOtelBuilder().WithStartupCheck(StartupPolicyFail, time.Second)
+			if tt.endpoint {
+				b.WithEndpointURL(server.URL + tt.path)
+			}
+
+			exporter, err := otlptracehttp.New(context.Background(), b.traceExporterOpts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer exporter.Shutdown(context.Background())
+			if err := exporter.ExportSpans(context.Background(), tracetest.SpanStubs{{Name: "probe"}}.Snapshots()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.probeEndpoint(context.Background()); err != nil {
+				t.Fatalf("probeEndpoint() error = %v", err)
+			}
+
+			if len(requests) != 2 {
+				t.Fatalf("got %d requests, want an export and a probe", len(requests))
+			}
+			export, probe := requests[0], requests[1]
+			if probe.URL.Path != export.URL.Path {
+				t.Errorf("probe path = %q, exporter path = %q", probe.URL.Path, export.URL.Path)
+			}
+			if got, want := probe.Header.Get("Authorization"), export.Header.Get("Authorization"); got != want {
+				t.Errorf("probe Authorization = %q, exporter Authorization = %q", got, want)
+			} else if tt.env["OTEL_EXPORTER_OTLP_HEADERS"] != "" && got != "Bearer token" {
+				t.Errorf("probe Authorization = %q, want the env header", got)
+			}
+		})
+	}
+}
+
+func TestProbeEndpointUsesEnvEndpoint(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", server.URL)
+
+	b := NewOtelBuilder().WithStartupCheck(StartupPolicyFail, time.Second)
+	if _, err := b.probeEndpoint(context.Background()); err != nil {
+		t.Fatalf("probeEndpoint() error = %v", err)
+	}
+	if gotPath != "/v1/traces" {
+		t.Errorf("probe path = %q, want /v1/traces", gotPath)
+	}
+}
+
+func TestProbeEndpointClassifiesAuthFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	b := NewOtelBuilder().WithEndpointURL(server.URL).WithStartupCheck(StartupPolicyFail, time.Second)
+	_, err := b.probeEndpoint(context.Background())
+	checkErr, ok := err.(*StartupCheckError)
+	if !ok {
+		t.Fatalf("probeEndpoint() error = %v, want *StartupCheckError", err)
+	}
+	if checkErr.Failure != StartupFailureAuth || checkErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got failure %q status %d, want auth 401", checkErr.Failure, checkErr.StatusCode)
+	}
+}
@@ -0,0 +1,190 @@
+package apw_tracez
+
+import (
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Query parameters of the debug page.
+const (
+	paramName   = "name"
+	paramType   = "type"
+	paramBucket = "bucket"
+)
+
+// Span list types selectable with the type query parameter.
+const (
+	typeRunning = "running"
+	typeLatency = "latency"
+	typeError   = "error"
+)
+
+// Handler returns an http.Handler rendering the debug page. Without query parameters it lists
+// every span name with its running spans, latency buckets and errors; each count links to the
+// matching spans with their attributes and events, including exception.* details.
+func (p *SpanProcessor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := pageData{
+			Path:    r.URL.Path,
+			Buckets: bucketLabels(),
+		}
+		page.Summaries = p.Summaries()
+
+		q := r.URL.Query()
+		if name := q.Get(paramName); name != "" {
+			page.Name = name
+			switch q.Get(paramType) {
+			case typeRunning:
+				page.Title = "Running"
+				page.Spans = viewSpans(p.RunningSpans(name))
+			case typeError:
+				page.Title = "Errors"
+				page.Spans = viewSpans(p.ErrorSpans(name))
+			default:
+				bucket, _ := strconv.Atoi(q.Get(paramBucket))
+				if bucket >= 0 && bucket < len(page.Buckets) {
+					page.Title = "Latency " + page.Buckets[bucket]
+				}
+				page.Spans = viewSpans(p.LatencySpans(name, bucket))
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := pageTemplate.Execute(w, page); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// GinHandler returns Handler for mounting on a gin router, e.g. router.GET("/debug/tracez", p.GinHandler()).
+func (p *SpanProcessor) GinHandler() gin.HandlerFunc {
+	return gin.WrapH(p.Handler())
+}
+
+type pageData struct {
+	Path      string
+	Buckets   []string
+	Summaries []Summary
+	Name      string
+	Title     string
+	Spans     []spanView
+}
+
+type spanView struct {
+	Start      string
+	Duration   string
+	TraceID    string
+	SpanID     string
+	ParentID   string
+	Kind       string
+	Status     string
+	Attributes []string
+	Events     []string
+}
+
+func viewSpans(spans []sdktrace.ReadOnlySpan) []spanView {
+	views := make([]spanView, 0, len(spans))
+	for _, s := range spans {
+		end := s.EndTime()
+		if end.IsZero() {
+			end = time.Now()
+		}
+		view := spanView{
+			Start:      s.StartTime().Format(time.RFC3339Nano),
+			Duration:   end.Sub(s.StartTime()).String(),
+			TraceID:    s.SpanContext().TraceID().String(),
+			SpanID:     s.SpanContext().SpanID().String(),
+			Kind:       s.SpanKind().String(),
+			Status:     s.Status().Code.String(),
+			Attributes: formatAttributes(s.Attributes()),
+		}
+		if s.Parent().IsValid() {
+			view.ParentID = s.Parent().SpanID().String()
+		}
+		if s.Status().Description != "" {
+			view.Status += ": " + s.Status().Description
+		}
+		for _, event := range s.Events() {
+			line := event.Time.Format(time.RFC3339Nano) + " " + event.Name
+			if attrs := formatAttributes(event.Attributes); len(attrs) > 0 {
+				line += " " + strings.Join(attrs, " ")
+			}
+			view.Events = append(view.Events, line)
+		}
+		views = append(views, view)
+	}
+	return views
+}
+
+func formatAttributes(attrs []attribute.KeyValue) []string {
+	out := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		out = append(out, string(attr.Key)+"="+attr.Value.Emit())
+	}
+	return out
+}
+
+// bucketLabels returns a label such as "[1ms, 10ms)" for every latency bucket.
+func bucketLabels() []string {
+	labels := make([]string, 0, len(LatencyBuckets)+1)
+	lower := time.Duration(0)
+	for _, upper := range LatencyBuckets {
+		labels = append(labels, "["+lower.String()+", "+upper.String()+")")
+		lower = upper
+	}
+	return append(labels, "["+lower.String()+", +Inf)")
+}
+
+var pageTemplate = template.Must(template.New("tracez").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>tracez</title>
+<style>
+body { font-family: sans-serif; font-size: 13px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+td.num { text-align: right; }
+pre { margin: 0; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>Spans</h1>
+<table>
+<tr><th>Name</th><th>Running</th>{{range .Buckets}}<th>{{.}}</th>{{end}}<th>Errors</th></tr>
+{{$path := .Path}}
+{{range .Summaries}}{{$name := .Name}}
+<tr>
+<td>{{.Name}}</td>
+<td class="num"><a href="{{$path}}?name={{.Name}}&type=running">{{.Running}}</a></td>
+{{range $i, $n := .Latency}}<td class="num"><a href="{{$path}}?name={{$name}}&type=latency&bucket={{$i}}">{{$n}}</a></td>{{end}}
+<td class="num"><a href="{{$path}}?name={{.Name}}&type=error">{{.Errors}}</a></td>
+</tr>
+{{end}}
+</table>
+{{if .Name}}
+<h2>{{.Name}}: {{.Title}}</h2>
+<table>
+<tr><th>Start</th><th>Duration</th><th>Trace ID</th><th>Span ID</th><th>Parent ID</th><th>Kind</th><th>Status</th><th>Attributes</th><th>Events</th></tr>
+{{range .Spans}}
+<tr>
+<td>{{.Start}}</td><td>{{.Duration}}</td><td>{{.TraceID}}</td><td>{{.SpanID}}</td><td>{{.ParentID}}</td><td>{{.Kind}}</td><td>{{.Status}}</td>
+<td><pre>{{range .Attributes}}{{.}}
+{{end}}</pre></td>
+<td><pre>{{range .Events}}{{.}}
+{{end}}</pre></td>
+</tr>
+{{else}}
+<tr><td colspan="9">No spans.</td></tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,256 @@
+// Package apw_tracez provides an in-process trace debug page in the style of tracez. A span
+// processor keeps the most recent spans of every span name in bounded ring buffers, and
+// Handler renders them, so that on-call engineers can see what a process is tracing even when
+// the collector is unreachable.
+package apw_tracez
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultSamplesPerBucket is the number of spans kept per latency bucket and for errors.
+	DefaultSamplesPerBucket = 10
+	// DefaultMaxSpanNames bounds the number of distinct span names tracked.
+	DefaultMaxSpanNames = 1000
+)
+
+// LatencyBuckets are the upper bounds of the latency buckets; the last bucket is unbounded.
+var LatencyBuckets = []time.Duration{
+	10 * time.Microsecond,
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+	100 * time.Second,
+}
+
+// Option configures the SpanProcessor.
+type Option func(*SpanProcessor)
+
+// WithSamplesPerBucket sets how many spans are kept per latency bucket and for errors.
+func WithSamplesPerBucket(n int) Option {
+	return func(p *SpanProcessor) {
+		if n > 0 {
+			p.samples = n
+		}
+	}
+}
+
+// WithMaxSpanNames sets how many distinct span names are tracked. Spans with further names
+// are not kept.
+func WithMaxSpanNames(n int) Option {
+	return func(p *SpanProcessor) {
+		if n > 0 {
+			p.maxNames = n
+		}
+	}
+}
+
+// SpanProcessor is a sdktrace.SpanProcessor that keeps recent and running spans for Handler.
+// Register it with OtelBuilder.WithSpanProcessor or sdktrace.WithSpanProcessor.
+type SpanProcessor struct {
+	samples  int
+	maxNames int
+
+	mu      sync.Mutex
+	running map[trace.SpanID]sdktrace.ReadOnlySpan
+	names   map[string]*spanNameData
+}
+
+// spanNameData holds the ended spans of one span name.
+type spanNameData struct {
+	latency      []*ring
+	latencyTotal []uint64
+	errors       *ring
+	errorTotal   uint64
+}
+
+// ring is a fixed-size buffer that overwrites its oldest span.
+type ring struct {
+	spans []sdktrace.ReadOnlySpan
+	next  int
+}
+
+func newRing(size int) *ring {
+	return &ring{spans: make([]sdktrace.ReadOnlySpan, 0, size)}
+}
+
+func (r *ring) add(s sdktrace.ReadOnlySpan) {
+	if len(r.spans) < cap(r.spans) {
+		r.spans = append(r.spans, s)
+		return
+	}
+	r.spans[r.next] = s
+	r.next = (r.next + 1) % len(r.spans)
+}
+
+// snapshot returns the kept spans, newest first.
+func (r *ring) snapshot() []sdktrace.ReadOnlySpan {
+	out := make([]sdktrace.ReadOnlySpan, 0, len(r.spans))
+	for i := len(r.spans) - 1; i >= 0; i-- {
+		out = append(out, r.spans[(r.next+i)%len(r.spans)])
+	}
+	return out
+}
+
+// NewSpanProcessor returns an empty SpanProcessor.
+func NewSpanProcessor(opts ...Option) *SpanProcessor {
+	p := &SpanProcessor{
+		samples:  DefaultSamplesPerBucket,
+		maxNames: DefaultMaxSpanNames,
+		running:  make(map[trace.SpanID]sdktrace.ReadOnlySpan),
+		names:    make(map[string]*spanNameData),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// OnStart tracks s as running.
+func (p *SpanProcessor) OnStart(_ context.Context, s sdktrace.ReadWriteSpan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.data(s.Name()) != nil {
+		p.running[s.SpanContext().SpanID()] = s
+	}
+}
+
+// OnEnd moves s from the running spans to its latency bucket, or to the errors.
+func (p *SpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.running, s.SpanContext().SpanID())
+
+	data := p.data(s.Name())
+	if data == nil {
+		return
+	}
+	if s.Status().Code == codes.Error {
+		data.errors.add(s)
+		data.errorTotal++
+		return
+	}
+	bucket := latencyBucket(s.EndTime().Sub(s.StartTime()))
+	data.latency[bucket].add(s)
+	data.latencyTotal[bucket]++
+}
+
+// Shutdown does nothing; kept spans stay available to Handler.
+func (p *SpanProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+// ForceFlush does nothing as spans are not exported.
+func (p *SpanProcessor) ForceFlush(context.Context) error {
+	return nil
+}
+
+// data returns the buffers of name, creating them while under the name limit. p.mu must be held.
+func (p *SpanProcessor) data(name string) *spanNameData {
+	if data, ok := p.names[name]; ok {
+		return data
+	}
+	if len(p.names) >= p.maxNames {
+		return nil
+	}
+	data := &spanNameData{
+		latency:      make([]*ring, len(LatencyBuckets)+1),
+		latencyTotal: make([]uint64, len(LatencyBuckets)+1),
+		errors:       newRing(p.samples),
+	}
+	for i := range data.latency {
+		data.latency[i] = newRing(p.samples)
+	}
+	p.names[name] = data
+	return data
+}
+
+// latencyBucket returns the index of the bucket holding d.
+func latencyBucket(d time.Duration) int {
+	for i, bound := range LatencyBuckets {
+		if d < bound {
+			return i
+		}
+	}
+	return len(LatencyBuckets)
+}
+
+// Summary is the per span name overview shown on the main page.
+type Summary struct {
+	Name    string
+	Running int
+	// Latency holds the number of spans ended in each latency bucket since start.
+	Latency []uint64
+	Errors  uint64
+}
+
+// Summaries returns one Summary per span name, sorted by name.
+func (p *SpanProcessor) Summaries() []Summary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	running := make(map[string]int)
+	for _, s := range p.running {
+		running[s.Name()]++
+	}
+
+	summaries := make([]Summary, 0, len(p.names))
+	for name, data := range p.names {
+		summaries = append(summaries, Summary{
+			Name:    name,
+			Running: running[name],
+			Latency: append([]uint64(nil), data.latencyTotal...),
+			Errors:  data.errorTotal,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries
+}
+
+// RunningSpans returns the spans named name that have not ended yet, oldest first.
+func (p *SpanProcessor) RunningSpans(name string) []sdktrace.ReadOnlySpan {
+	p.mu.Lock()
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range p.running {
+		if s.Name() == name {
+			spans = append(spans, s)
+		}
+	}
+	p.mu.Unlock()
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].StartTime().Before(spans[j].StartTime()) })
+	return spans
+}
+
+// LatencySpans returns the kept spans named name in latency bucket bucket, newest first.
+func (p *SpanProcessor) LatencySpans(name string, bucket int) []sdktrace.ReadOnlySpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, ok := p.names[name]
+	if !ok || bucket < 0 || bucket >= len(data.latency) {
+		return nil
+	}
+	return data.latency[bucket].snapshot()
+}
+
+// ErrorSpans returns the kept failed spans named name, newest first.
+func (p *SpanProcessor) ErrorSpans(name string) []sdktrace.ReadOnlySpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	data, ok := p.names[name]
+	if !ok {
+		return nil
+	}
+	return data.errors.snapshot()
+}
@@ -0,0 +1,150 @@
+package apw_tracez
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer(p *SpanProcessor) trace.Tracer {
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(p)).Tracer("test")
+}
+
+// endAfter records a span called name lasting d, ended with an error status when failed.
+func endAfter(tracer trace.Tracer, name string, d time.Duration, failed bool) trace.Span {
+	start := time.Now()
+	_, span := tracer.Start(context.Background(), name, trace.WithTimestamp(start))
+	if failed {
+		span.SetStatus(codes.Error, "boom")
+	}
+	span.End(trace.WithTimestamp(start.Add(d)))
+	return span
+}
+
+func TestSpanProcessorBucketsSpans(t *testing.T) {
+	p := NewSpanProcessor()
+	tracer := newTestTracer(p)
+
+	endAfter(tracer, "query", 5*time.Millisecond, false)
+	endAfter(tracer, "query", 5*time.Millisecond, false)
+	endAfter(tracer, "query", 2*time.Second, false)
+	endAfter(tracer, "query", time.Millisecond, true)
+	_, running := tracer.Start(context.Background(), "query")
+
+	summaries := p.Summaries()
+	if len(summaries) != 1 || summaries[0].Name != "query" {
+		t.Fatalf("summaries = %+v, want one for query", summaries)
+	}
+	s := summaries[0]
+	if s.Running != 1 || s.Errors != 1 {
+		t.Errorf("running = %d, errors = %d, want 1 and 1", s.Running, s.Errors)
+	}
+	if s.Latency[latencyBucket(5*time.Millisecond)] != 2 || s.Latency[latencyBucket(2*time.Second)] != 1 {
+		t.Errorf("latency counts = %v", s.Latency)
+	}
+	if got := p.RunningSpans("query"); len(got) != 1 || got[0].SpanContext().SpanID() != running.SpanContext().SpanID() {
+		t.Errorf("running spans = %v, want the unended span", got)
+	}
+
+	running.End()
+	if got := p.Summaries()[0].Running; got != 0 {
+		t.Errorf("running = %d after End, want 0", got)
+	}
+}
+
+func TestLatencyBucketBounds(t *testing.T) {
+	tests := map[time.Duration]int{
+		0:                      0,
+		10 * time.Microsecond:  1,
+		999 * time.Microsecond: 2,
+		time.Millisecond:       3,
+		time.Hour:              len(LatencyBuckets),
+	}
+	for d, want := range tests {
+		if got := latencyBucket(d); got != want {
+			t.Errorf("latencyBucket(%s) = %d, want %d", d, got, want)
+		}
+	}
+}
+
+func TestSpanProcessorKeepsNewestSamples(t *testing.T) {
+	p := NewSpanProcessor(WithSamplesPerBucket(2))
+	tracer := newTestTracer(p)
+
+	var ids []trace.SpanID
+	for i := 0; i < 3; i++ {
+		ids = append(ids, endAfter(tracer, "query", time.Millisecond, true).SpanContext().SpanID())
+	}
+
+	spans := p.ErrorSpans("query")
+	if len(spans) != 2 || spans[0].SpanContext().SpanID() != ids[2] || spans[1].SpanContext().SpanID() != ids[1] {
+		t.Errorf("error spans = %v, want the two newest, newest first", spans)
+	}
+	if got := p.Summaries()[0].Errors; got != 3 {
+		t.Errorf("error total = %d, want 3 including the dropped sample", got)
+	}
+}
+
+func TestSpanProcessorLimitsSpanNames(t *testing.T) {
+	p := NewSpanProcessor(WithMaxSpanNames(1))
+	tracer := newTestTracer(p)
+
+	endAfter(tracer, "first", time.Millisecond, false)
+	_, span := tracer.Start(context.Background(), "second")
+	defer span.End()
+
+	if summaries := p.Summaries(); len(summaries) != 1 || summaries[0].Name != "first" {
+		t.Errorf("summaries = %+v, want only first", summaries)
+	}
+	if got := p.RunningSpans("second"); len(got) != 0 {
+		t.Errorf("running spans = %v, want none beyond the name limit", got)
+	}
+}
+
+func get(t *testing.T, p *SpanProcessor, target string) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	if rec.Code != 200 {
+		t.Fatalf("GET %s = %d", target, rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestHandlerRendersSummariesAndSpans(t *testing.T) {
+	p := NewSpanProcessor()
+	tracer := newTestTracer(p)
+
+	start := time.Now()
+	_, span := tracer.Start(context.Background(), "<checkout>", trace.WithTimestamp(start))
+	span.SetAttributes(attribute.String("order.id", "o-1"))
+	span.AddEvent("exception", trace.WithAttributes(attribute.String("exception.message", "card declined")))
+	span.SetStatus(codes.Error, "payment failed")
+	span.End(trace.WithTimestamp(start.Add(time.Millisecond)))
+
+	page := get(t, p, "/debug/tracez")
+	if !strings.Contains(page, "&lt;checkout&gt;") || strings.Contains(page, "<checkout>") {
+		t.Error("summary page does not list the escaped span name")
+	}
+
+	page = get(t, p, "/debug/tracez?name=%3Ccheckout%3E&type=error")
+	for _, want := range []string{
+		span.SpanContext().TraceID().String(),
+		"order.id=o-1",
+		"exception.message=card declined",
+		"payment failed",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("error page lacks %q", want)
+		}
+	}
+}
@@ -0,0 +1,383 @@
+package _tracing
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// attributeTag is the struct tag read by AddAttributesFrom, e.g. `otel:"user.id,omitempty"`.
+	attributeTag = "otel"
+	// RedactedValue replaces the value of struct fields tagged with the redact option.
+	RedactedValue = "[REDACTED]"
+	// maxFlattenDepth stops AddAttributesFrom from following self-referencing values forever.
+	maxFlattenDepth = 8
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// ToAttribute converts value into an attribute using the most specific attribute type
+// available. Integers, floats, booleans, strings and their slices map to the matching kinds,
+// including named types such as `type UserID int64`, time.Duration to seconds as a float,
+// time.Time to an RFC 3339 string, and anything else to its fmt representation. Byte slices,
+// such as json.RawMessage, become a string: the bytes themselves when they are valid UTF-8,
+// standard base64 otherwise.
+func ToAttribute(key string, value any) attribute.KeyValue {
+	switch v := value.(type) {
+	case nil:
+		return attribute.String(key, "")
+	case attribute.Value:
+		return attribute.KeyValue{Key: attribute.Key(key), Value: v}
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int64(key, int64(v))
+	case int16:
+		return attribute.Int64(key, int64(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint:
+		return uintAttribute(key, uint64(v))
+	case uint8:
+		return attribute.Int64(key, int64(v))
+	case uint16:
+		return attribute.Int64(key, int64(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint64:
+		return uintAttribute(key, v)
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case time.Duration:
+		return attribute.Float64(key, v.Seconds())
+	case time.Time:
+		return attribute.String(key, v.Format(time.RFC3339Nano))
+	case []byte:
+		return bytesAttribute(key, v)
+	case []string:
+		return attribute.StringSlice(key, v)
+	case []bool:
+		return attribute.BoolSlice(key, v)
+	case []int:
+		return attribute.IntSlice(key, v)
+	case []int64:
+		return attribute.Int64Slice(key, v)
+	case []float64:
+		return attribute.Float64Slice(key, v)
+	case []time.Duration:
+		seconds := make([]float64, len(v))
+		for i, d := range v {
+			seconds[i] = d.Seconds()
+		}
+		return attribute.Float64Slice(key, seconds)
+	case error:
+		return attribute.String(key, v.Error())
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return attribute.String(key, "")
+		}
+		return ToAttribute(key, rv.Elem().Interface())
+	}
+	if attr, ok := kindAttribute(key, rv); ok {
+		return attr
+	}
+	return attribute.String(key, fmt.Sprintf("%v", value))
+}
+
+// kindAttribute converts rv by its kind rather than its type, so named basic types and slices
+// keep their numeric, boolean or string attribute type. It only uses reflect accessors, which
+// also work on values read through unexported embedded structs.
+func kindAttribute(key string, rv reflect.Value) (attribute.KeyValue, bool) {
+	if rv.Type() == durationType {
+		return attribute.Float64(key, time.Duration(rv.Int()).Seconds()), true
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return attribute.String(key, rv.String()), true
+	case reflect.Bool:
+		return attribute.Bool(key, rv.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return attribute.Int64(key, rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintAttribute(key, rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return attribute.Float64(key, rv.Float()), true
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return bytesAttribute(key, rv.Bytes()), true
+		}
+		return sliceAttribute(key, rv)
+	}
+	return attribute.KeyValue{}, false
+}
+
+// bytesAttribute records b as text when it is valid UTF-8, which exporters require of string
+// values, and as standard base64 otherwise.
+func bytesAttribute(key string, b []byte) attribute.KeyValue {
+	if utf8.Valid(b) {
+		return attribute.String(key, string(b))
+	}
+	return attribute.String(key, base64.StdEncoding.EncodeToString(b))
+}
+
+// uintAttribute keeps unsigned values numeric while they fit in an int64.
+func uintAttribute(key string, v uint64) attribute.KeyValue {
+	if v > math.MaxInt64 {
+		return attribute.String(key, strconv.FormatUint(v, 10))
+	}
+	return attribute.Int64(key, int64(v))
+}
+
+// sliceAttribute converts slices and arrays of other numeric or string element types
+// (for example []int32 or [3]string) into the matching slice attribute.
+func sliceAttribute(key string, rv reflect.Value) (attribute.KeyValue, bool) {
+	n := rv.Len()
+	switch rv.Type().Elem().Kind() {
+	case reflect.String:
+		values := make([]string, n)
+		for i := range values {
+			values[i] = rv.Index(i).String()
+		}
+		return attribute.StringSlice(key, values), true
+	case reflect.Bool:
+		values := make([]bool, n)
+		for i := range values {
+			values[i] = rv.Index(i).Bool()
+		}
+		return attribute.BoolSlice(key, values), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		values := make([]int64, n)
+		for i := range values {
+			values[i] = rv.Index(i).Int()
+		}
+		return attribute.Int64Slice(key, values), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return uintSliceAttribute(key, rv), true
+	case reflect.Float32, reflect.Float64:
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = rv.Index(i).Float()
+		}
+		return attribute.Float64Slice(key, values), true
+	}
+	return attribute.KeyValue{}, false
+}
+
+// uintSliceAttribute keeps unsigned slices numeric while every element fits in an int64, and
+// falls back to decimal strings otherwise so no element wraps around.
+func uintSliceAttribute(key string, rv reflect.Value) attribute.KeyValue {
+	n := rv.Len()
+	values := make([]int64, n)
+	for i := range values {
+		v := rv.Index(i).Uint()
+		if v > math.MaxInt64 {
+			strs := make([]string, n)
+			for j := range strs {
+				strs[j] = strconv.FormatUint(rv.Index(j).Uint(), 10)
+			}
+			return attribute.StringSlice(key, strs)
+		}
+		values[i] = int64(v)
+	}
+	return attribute.Int64Slice(key, values)
+}
+
+// AddAttributesFrom flattens a struct or map into span attributes with dotted keys. Struct
+// fields are named by their `otel` tag, falling back to the field name; the tag options
+// omitempty (skip zero values) and redact (replace the value with RedactedValue) are
+// supported, and a tag of "-" skips the field. Fields of an embedded struct without a tag name
+// are promoted to the embedding struct, as encoding/json does.
+func (t *tracing) AddAttributesFrom(span trace.Span, v any) {
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(t.limitAttributes(FlattenAttributes(v))...)
+}
+
+// FlattenAttributes returns the attributes AddAttributesFrom would set for v.
+func FlattenAttributes(v any) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	flatten(&attrs, "", reflect.ValueOf(v), 0)
+	return attrs
+}
+
+func flatten(attrs *[]attribute.KeyValue, prefix string, rv reflect.Value, depth int) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return
+	}
+
+	if depth > maxFlattenDepth || rv.Type() == timeType || rv.Type() == durationType {
+		appendLeaf(attrs, prefix, rv)
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		flattenStruct(attrs, prefix, rv, depth)
+	case reflect.Map:
+		flattenMap(attrs, prefix, rv, depth)
+	case reflect.Slice, reflect.Array:
+		if isNested(rv.Type().Elem()) {
+			for i := 0; i < rv.Len(); i++ {
+				flatten(attrs, joinKey(prefix, strconv.Itoa(i)), rv.Index(i), depth+1)
+			}
+			return
+		}
+		appendLeaf(attrs, prefix, rv)
+	default:
+		appendLeaf(attrs, prefix, rv)
+	}
+}
+
+func flattenStruct(attrs *[]attribute.KeyValue, prefix string, rv reflect.Value, depth int) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if isPromoted(field) {
+			flatten(attrs, prefix, rv.Field(i), depth+1)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, redact := parseAttributeTag(field)
+		if name == "-" {
+			continue
+		}
+
+		value := rv.Field(i)
+		if omitEmpty && value.IsZero() {
+			continue
+		}
+
+		key := joinKey(prefix, name)
+		if redact {
+			*attrs = append(*attrs, attribute.String(key, RedactedValue))
+			continue
+		}
+		flatten(attrs, key, value, depth+1)
+	}
+}
+
+func flattenMap(attrs *[]attribute.KeyValue, prefix string, rv reflect.Value, depth int) {
+	keys := make([]string, 0, rv.Len())
+	values := make(map[string]reflect.Value, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		key := fmt.Sprintf("%v", iter.Key().Interface())
+		keys = append(keys, key)
+		values[key] = iter.Value()
+	}
+
+	// Sort keys so the attribute order is stable between calls.
+	sort.Strings(keys)
+	for _, key := range keys {
+		flatten(attrs, joinKey(prefix, key), values[key], depth+1)
+	}
+}
+
+func appendLeaf(attrs *[]attribute.KeyValue, key string, rv reflect.Value) {
+	if key == "" {
+		return
+	}
+	if rv.CanInterface() {
+		*attrs = append(*attrs, ToAttribute(key, rv.Interface()))
+		return
+	}
+	// Promoted fields of an unexported embedded struct cannot be turned back into an interface.
+	if attr, ok := kindAttribute(key, rv); ok {
+		*attrs = append(*attrs, attr)
+	}
+}
+
+// parseAttributeTag returns the attribute name and options of a struct field.
+func parseAttributeTag(field reflect.StructField) (name string, omitEmpty, redact bool) {
+	tag := field.Tag.Get(attributeTag)
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		switch strings.TrimSpace(option) {
+		case "omitempty":
+			omitEmpty = true
+		case "redact":
+			redact = true
+		}
+	}
+	return name, omitEmpty, redact
+}
+
+// isPromoted reports whether field is an embedded struct, or pointer to one, whose fields are
+// flattened as if they were declared on the embedding struct.
+func isPromoted(field reflect.StructField) bool {
+	if !field.Anonymous {
+		return false
+	}
+	if name, _, _ := strings.Cut(field.Tag.Get(attributeTag), ","); name != "" {
+		return false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// isNested reports whether values of type t are flattened into several keys rather than
+// converted into a single attribute.
+func isNested(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType || t == durationType {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,89 @@
+package _tracing
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"otel-library/errs"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type userID int64
+
+func TestToAttribute(t *testing.T) {
+	tests := map[string]struct {
+		value any
+		want  attribute.Value
+	}{
+		"named int":         {value: userID(42), want: attribute.Int64Value(42)},
+		"named string":      {value: errs.Status("NOT_FOUND"), want: attribute.StringValue("NOT_FOUND")},
+		"uint slice":        {value: []uint{1, 2}, want: attribute.Int64SliceValue([]int64{1, 2})},
+		"uint64 slice":      {value: []uint64{3, 4}, want: attribute.Int64SliceValue([]int64{3, 4})},
+		"uint64 overflow":   {value: []uint64{1, math.MaxUint64}, want: attribute.StringSliceValue([]string{"1", "18446744073709551615"})},
+		"named int slice":   {value: []userID{5}, want: attribute.Int64SliceValue([]int64{5})},
+		"pointer to named":  {value: func() *userID { id := userID(7); return &id }(), want: attribute.Int64Value(7)},
+		"unsupported value": {value: struct{ A int }{A: 1}, want: attribute.StringValue("{1}")},
+		"bytes":             {value: []byte("hello"), want: attribute.StringValue("hello")},
+		"json raw message":  {value: json.RawMessage(`{"id":1}`), want: attribute.StringValue(`{"id":1}`)},
+		"binary bytes":      {value: []byte{0xff, 0x00}, want: attribute.StringValue("/wA=")},
+		"byte array":        {value: [2]byte{1, 2}, want: attribute.Int64SliceValue([]int64{1, 2})},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := ToAttribute("key", tt.value)
+			if got.Value != tt.want {
+				t.Errorf("ToAttribute(%#v) = %v (%s), want %v (%s)",
+					tt.value, got.Value.Emit(), got.Value.Type(), tt.want.Emit(), tt.want.Type())
+			}
+		})
+	}
+}
+
+type auditInfo struct {
+	CreatedBy string `otel:"created_by"`
+}
+
+type Tenant struct {
+	TenantID userID `otel:"tenant.id"`
+}
+
+type Order struct {
+	Tenant
+	*auditInfo
+	Customer Tenant `otel:"customer"`
+	ID       string `otel:"id"`
+}
+
+func TestFlattenAttributesPromotesEmbeddedFields(t *testing.T) {
+	got := FlattenAttributes(Order{
+		Tenant:    Tenant{TenantID: 1},
+		auditInfo: &auditInfo{CreatedBy: "alice"},
+		Customer:  Tenant{TenantID: 2},
+		ID:        "o-1",
+	})
+
+	want := []attribute.KeyValue{
+		attribute.Int64("tenant.id", 1),
+		attribute.String("created_by", "alice"),
+		attribute.Int64("customer.tenant.id", 2),
+		attribute.String("id", "o-1"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenAttributes = %v, want %v", got, want)
+	}
+}
+
+func TestFlattenAttributesKeepsRawJSONAsString(t *testing.T) {
+	type event struct {
+		Payload json.RawMessage `otel:"payload"`
+	}
+
+	got := FlattenAttributes(event{Payload: json.RawMessage(`{"id":1}`)})
+	want := []attribute.KeyValue{attribute.String("payload", `{"id":1}`)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenAttributes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package _tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+const (
+	// DefaultBaggageMaxEntries is the W3C limit on the number of baggage entries.
+	DefaultBaggageMaxEntries = 180
+	// DefaultBaggageMaxBytes is the W3C limit on the encoded size of the baggage header.
+	DefaultBaggageMaxBytes = 8192
+)
+
+// ErrBaggageLimit is returned by SetBaggage when adding the entry would exceed the configured
+// entry count or size limit.
+var ErrBaggageLimit = errors.New("baggage limit exceeded")
+
+// WithBaggageLimits sets the maximum number of baggage entries and the maximum encoded size
+// in bytes enforced by SetBaggage. Non-positive values keep the defaults.
+func WithBaggageLimits(maxEntries, maxBytes int) Option {
+	return func(t *tracing) {
+		if maxEntries > 0 {
+			t.baggageMaxEntries = maxEntries
+		}
+		if maxBytes > 0 {
+			t.baggageMaxBytes = maxBytes
+		}
+	}
+}
+
+// WithBaggagePromotion copies the listed baggage keys onto every span started through this
+// OtelTracing, so that a value such as tenant.id set at the edge shows up downstream.
+func WithBaggagePromotion(keys ...string) Option {
+	return func(t *tracing) {
+		t.baggageKeys = append(t.baggageKeys, keys...)
+	}
+}
+
+// SetBaggage returns a copy of ctx whose baggage holds key=value. The value is stored as is and
+// percent-encoded when propagated. An error is returned for an invalid key, or when the
+// baggage would exceed the configured limits, in which case ctx is returned unchanged.
+func (t *tracing) SetBaggage(ctx context.Context, key, value string) (context.Context, error) {
+	member, err := baggage.NewMemberRaw(key, value)
+	if err != nil {
+		return ctx, fmt.Errorf("invalid baggage entry %q: %w", key, err)
+	}
+
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, fmt.Errorf("invalid baggage entry %q: %w", key, err)
+	}
+	if bag.Len() > t.baggageMaxEntries {
+		return ctx, fmt.Errorf("%w: more than %d entries", ErrBaggageLimit, t.baggageMaxEntries)
+	}
+	if size := len(bag.String()); size > t.baggageMaxBytes {
+		return ctx, fmt.Errorf("%w: %d bytes exceeds %d", ErrBaggageLimit, size, t.baggageMaxBytes)
+	}
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
+
+// GetBaggage returns the baggage value stored under key, or an empty string.
+func (t *tracing) GetBaggage(ctx context.Context, key string) string {
+	return baggage.FromContext(ctx).Member(key).Value()
+}
+
+// RemoveBaggage returns a copy of ctx whose baggage no longer holds key.
+func (t *tracing) RemoveBaggage(ctx context.Context, key string) context.Context {
+	bag := baggage.FromContext(ctx)
+	if bag.Member(key).Key() == "" {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag.DeleteMember(key))
+}
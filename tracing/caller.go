@@ -0,0 +1,95 @@
+package _tracing
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// callerInfo describes a call site: the span name derived from it and its code.* attributes.
+type callerInfo struct {
+	spanName string
+	attrs    []attribute.KeyValue
+}
+
+// callerCache maps a program counter to its *callerInfo so that symbolization happens once
+// per call site.
+var callerCache sync.Map
+
+// unknownCaller is used when the call stack cannot be inspected.
+var unknownCaller = &callerInfo{spanName: "unknown"}
+
+// StartSpanAuto starts a span named after the calling function, as package.Type.Method or
+// package.Function, with code.function, code.namespace, code.filepath and code.lineno set.
+func (t *tracing) StartSpanAuto(ctx context.Context, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	caller := lookupCaller(1)
+	opts = append(opts[:len(opts):len(opts)], trace.WithAttributes(caller.attrs...))
+	return t.StartSpan(ctx, caller.spanName, opts...)
+}
+
+// lookupCaller returns the call site skip frames above lookupCaller's caller.
+func lookupCaller(skip int) *callerInfo {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return unknownCaller
+	}
+
+	if cached, ok := callerCache.Load(pcs[0]); ok {
+		return cached.(*callerInfo)
+	}
+
+	frame, _ := runtime.CallersFrames(pcs[:]).Next()
+	info := newCallerInfo(frame)
+	callerCache.Store(pcs[0], info)
+	return info
+}
+
+func newCallerInfo(frame runtime.Frame) *callerInfo {
+	namespace, function, spanName := splitFunctionName(frame.Function)
+	return &callerInfo{
+		spanName: spanName,
+		attrs: []attribute.KeyValue{
+			semconv.CodeFunction(function),
+			semconv.CodeNamespace(namespace),
+			semconv.CodeFilepath(frame.File),
+			semconv.CodeLineNumber(frame.Line),
+		},
+	}
+}
+
+// splitFunctionName splits a fully qualified function name such as
+// "otel-library/cmd.(*Server).Handle" into its namespace ("otel-library/cmd.Server"), function
+// ("Handle") and span name ("cmd.Server.Handle").
+func splitFunctionName(fullName string) (namespace, function, spanName string) {
+	if fullName == "" {
+		return "", "", unknownCaller.spanName
+	}
+
+	pkgPath := ""
+	rest := fullName
+	if slash := strings.LastIndexByte(fullName, '/'); slash >= 0 {
+		pkgPath = fullName[:slash+1]
+		rest = fullName[slash+1:]
+	}
+
+	pkgName, symbol, ok := strings.Cut(rest, ".")
+	if !ok {
+		return pkgPath + pkgName, rest, rest
+	}
+
+	symbol = strings.NewReplacer("(*", "", "(", "", ")", "").Replace(symbol)
+	spanName = pkgName + "." + symbol
+
+	namespace = pkgPath + pkgName
+	function = symbol
+	if dot := strings.IndexByte(symbol, '.'); dot >= 0 {
+		namespace += "." + symbol[:dot]
+		function = symbol[dot+1:]
+	}
+	return namespace, function, spanName
+}
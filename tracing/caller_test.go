@@ -0,0 +1,58 @@
+package _tracing
+
+import (
+	"context"
+	"testing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func TestSplitFunctionName(t *testing.T) {
+	tests := []struct {
+		fullName, namespace, function, spanName string
+	}{
+		{"otel-library/cmd.(*Server).Handle", "otel-library/cmd.Server", "Handle", "cmd.Server.Handle"},
+		{"otel-library/cmd.Value.String", "otel-library/cmd.Value", "String", "cmd.Value.String"},
+		{"otel-library/cmd.main", "otel-library/cmd", "main", "cmd.main"},
+		{"main.main", "main", "main", "main.main"},
+		{"", "", "", "unknown"},
+	}
+	for _, tt := range tests {
+		namespace, function, spanName := splitFunctionName(tt.fullName)
+		if namespace != tt.namespace || function != tt.function || spanName != tt.spanName {
+			t.Errorf("splitFunctionName(%q) = %q, %q, %q; want %q, %q, %q",
+				tt.fullName, namespace, function, spanName, tt.namespace, tt.function, tt.spanName)
+		}
+	}
+}
+
+type autoNamed struct {
+	t OtelTracing
+}
+
+func (a *autoNamed) Handle(ctx context.Context) {
+	_, span := a.t.StartSpanAuto(ctx)
+	span.End()
+}
+
+func TestStartSpanAutoNamesSpanAfterCaller(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	(&autoNamed{t: tr}).Handle(context.Background())
+
+	span := lastSpan(t, recorder)
+	if span.Name() != "tracing.autoNamed.Handle" {
+		t.Errorf("span name = %q, want tracing.autoNamed.Handle", span.Name())
+	}
+	want := map[string]string{
+		string(semconv.CodeFunctionKey):  "Handle",
+		string(semconv.CodeNamespaceKey): "otel-library/tracing.autoNamed",
+	}
+	for _, attr := range span.Attributes() {
+		if value, ok := want[string(attr.Key)]; ok && attr.Value.AsString() != value {
+			t.Errorf("%s = %q, want %q", attr.Key, attr.Value.AsString(), value)
+		}
+	}
+	if !hasAttributeKey(span, string(semconv.CodeLineNumberKey)) || !hasAttributeKey(span, string(semconv.CodeFilepathKey)) {
+		t.Errorf("attributes %v lack the code location", span.Attributes())
+	}
+}
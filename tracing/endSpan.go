@@ -0,0 +1,37 @@
+package _tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EndSpanWithError ends span after setting its status from the error err points to, typically
+// a named return value:
+//
+//	func (s *Service) Do(ctx context.Context) (err error) {
+//		ctx, span := s.t.StartSpan(ctx, "Do")
+//		defer s.t.EndSpanWithError(span, &err)
+//
+// A nil error marks the span OK unless a status was already set on it. An *errs.ErrorService,
+// found with errs.ExtractErrorDetails, with a status below 400 is recorded as a handled error;
+// any other error through RecordError.
+func (t *tracing) EndSpanWithError(span trace.Span, err *error, opts ...trace.SpanEndOption) {
+	if span.IsRecording() {
+		var e error
+		if err != nil {
+			e = *err
+		}
+		setStatusFromError(trace.ContextWithSpan(context.Background(), span), t, span, e)
+	}
+	span.End(opts...)
+}
+
+// EndSpanWithStatus ends span after recording the HTTP status code statusCode points to with
+// SetHTTPStatus. A nil pointer or a zero status code leaves the status untouched.
+func (t *tracing) EndSpanWithStatus(span trace.Span, statusCode *int, opts ...trace.SpanEndOption) {
+	if span.IsRecording() && statusCode != nil && *statusCode != 0 {
+		t.SetHTTPStatus(span, *statusCode, spanKind(span))
+	}
+	span.End(opts...)
+}
@@ -0,0 +1,85 @@
+package _tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEndSpanWithErrorReadsNamedReturn(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	errBoom := errors.New("boom")
+
+	do := func(fail bool) (err error) {
+		_, span := tr.StartSpan(context.Background(), "do")
+		defer tr.EndSpanWithError(span, &err)
+		if fail {
+			return errBoom
+		}
+		return nil
+	}
+
+	do(false)
+	if got := lastSpan(t, recorder).Status().Code; got != codes.Ok {
+		t.Errorf("status = %v without an error, want Ok", got)
+	}
+	do(true)
+	ended := lastSpan(t, recorder)
+	if ended.Status().Code != codes.Error || ended.Status().Description != "boom" {
+		t.Errorf("status = %+v, want the returned error", ended.Status())
+	}
+
+	_, span := tr.StartSpan(context.Background(), "nil pointer")
+	tr.EndSpanWithError(span, nil)
+	if got := lastSpan(t, recorder).Status().Code; got != codes.Ok {
+		t.Errorf("status = %v with a nil pointer, want Ok", got)
+	}
+}
+
+func TestEndSpanWithStatus(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+
+	tests := []struct {
+		name   string
+		status *int
+		want   codes.Code
+	}{
+		{"server error", intPtr(500), codes.Error},
+		{"client error on server span", intPtr(404), codes.Unset},
+		{"unset", intPtr(0), codes.Unset},
+		{"nil", nil, codes.Unset},
+	}
+	for _, tt := range tests {
+		_, span := tr.StartSpan(context.Background(), tt.name, trace.WithSpanKind(trace.SpanKindServer))
+		tr.EndSpanWithStatus(span, tt.status)
+
+		ended := lastSpan(t, recorder)
+		if ended.EndTime().IsZero() || ended.Status().Code != tt.want {
+			t.Errorf("%s: status = %v, want %v", tt.name, ended.Status().Code, tt.want)
+		}
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestEndSpanWithErrorKeepsEarlierStatus(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+
+	do := func() (err error) {
+		_, span := tr.StartSpan(context.Background(), "do")
+		defer tr.EndSpanWithError(span, &err)
+		tr.SetHTTPStatus(span, http.StatusBadGateway, trace.SpanKindServer)
+		return nil
+	}
+
+	do()
+	if got := lastSpan(t, recorder).Status().Code; got != codes.Error {
+		t.Errorf("status = %v, want the Error set by SetHTTPStatus", got)
+	}
+}
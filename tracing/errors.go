@@ -0,0 +1,96 @@
+package _tracing
+
+import (
+	"fmt"
+
+	"otel-library/errs"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// asErrorService returns the first *errs.ErrorService found in err's chain, or nil.
+func asErrorService(err error) *errs.ErrorService {
+	return errs.ExtractErrorDetails(err)
+}
+
+// leafErrors splits err into the errors it joins. Joined errors (errors.Join or
+// fmt.Errorf with several %w verbs) are expanded recursively; any other error,
+// including one that only wraps a single error, is returned as is.
+func leafErrors(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		var leaves []error
+		for _, inner := range e.Unwrap() {
+			if inner != nil {
+				leaves = append(leaves, leafErrors(inner)...)
+			}
+		}
+		return leaves
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			if leaves := leafErrors(inner); len(leaves) > 1 {
+				return leaves
+			}
+		}
+	}
+	return []error{err}
+}
+
+// errorTypeName returns the Go type name reported as exception.type. When the chain holds an
+// *errs.ErrorService its type is used, so wrapping does not hide it.
+func errorTypeName(err error) string {
+	if errService := asErrorService(err); errService != nil {
+		return fmt.Sprintf("%T", errService)
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+// exceptionSpanAttributes returns the exception.* span attributes for err. The stack trace is
+// only available when the chain holds an *errs.ErrorService created with stacks enabled.
+func exceptionSpanAttributes(err error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.ExceptionMessageKey.String(err.Error()),
+		semconv.ExceptionTypeKey.String(errorTypeName(err)),
+	}
+	if errService := asErrorService(err); errService != nil {
+		if stack := errService.GetStackTrace(); stack != "" {
+			attrs = append(attrs, semconv.ExceptionStacktraceKey.String(stack))
+		}
+	}
+	return attrs
+}
+
+// exceptionAttributes describes a single error for an exception event, following the semantic
+// conventions used by span.RecordError plus the code and status of an *errs.ErrorService.
+func exceptionAttributes(err error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.ExceptionTypeKey.String(errorTypeName(err)),
+		semconv.ExceptionMessageKey.String(err.Error()),
+	}
+	if errService := asErrorService(err); errService != nil {
+		attrs = append(attrs,
+			attribute.String("exception.code", errService.ErrorCode),
+			attribute.String("exception.status", errService.StatusText),
+		)
+		if stack := errService.GetStackTrace(); stack != "" {
+			attrs = append(attrs, semconv.ExceptionStacktraceKey.String(stack))
+		}
+	}
+	return attrs
+}
+
+// handledErrorAttributes describes a single error for an ErrorHandled event.
+func handledErrorAttributes(err error) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.String("error.type", errorTypeName(err)),
+		attribute.String("error.message", err.Error()),
+	}
+	if errService := asErrorService(err); errService != nil {
+		attrs = append(attrs,
+			attribute.String("error.code", errService.ErrorCode),
+			attribute.String("error.status", errService.StatusText),
+		)
+	}
+	return attrs
+}
@@ -0,0 +1,127 @@
+package _tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queueWaitKey records how long a goroutine waited between being spawned and starting to run.
+const queueWaitKey = "goroutine.queue_wait"
+
+// Go runs fn in a new goroutine inside a child span of the span in ctx. A panic in fn is
+// recovered and recorded as a span error instead of crashing the process; the returned
+// error sets the span status as in Run.
+func (t *tracing) Go(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	spawned := time.Now()
+	go func() {
+		_ = runGoroutine(ctx, t, name, spawned, false, fn)
+	}()
+}
+
+// GoDetached runs fn in a new goroutine for work that outlives the caller, such as a
+// background job started from a request handler. The span starts a new trace linked to the
+// span in ctx, and fn's context keeps ctx's values but is not canceled with it.
+func (t *tracing) GoDetached(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	spawned := time.Now()
+	go func() {
+		_ = runGoroutine(ctx, t, name, spawned, true, fn)
+	}()
+}
+
+// runGoroutine runs fn in a span started on the current goroutine and converts a panic into
+// the returned error.
+func runGoroutine(ctx context.Context, t OtelTracing, name string, spawned time.Time, detached bool, fn func(ctx context.Context) error) (err error) {
+	opts := []trace.SpanStartOption{
+		trace.WithAttributes(ToAttribute(queueWaitKey, time.Since(spawned))),
+	}
+	if detached {
+		parent := trace.SpanContextFromContext(ctx)
+		ctx = context.WithoutCancel(ctx)
+		opts = append(opts, trace.WithNewRoot())
+		if parent.IsValid() {
+			opts = append(opts, trace.WithLinks(trace.Link{SpanContext: parent}))
+		}
+	}
+
+	ctx, span := t.StartSpan(ctx, name, opts...)
+	defer t.EndSpan(span)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in goroutine %q: %v", name, r)
+			span.RecordError(err, trace.WithStackTrace(true))
+			t.SetStatus(span, codes.Error, err.Error())
+		}
+	}()
+
+	err = fn(ctx)
+	setStatusFromError(ctx, t, span, err)
+	return err
+}
+
+// Group is a traced counterpart of errgroup.Group. Every function started with Go runs in its
+// own child span; the first error, or recovered panic, cancels the group's context and is
+// returned by Wait.
+type Group struct {
+	t      OtelTracing
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup returns a Group whose goroutines start spans with t, and the derived context that is
+// canceled when a goroutine fails or Wait returns.
+func NewGroup(ctx context.Context, t OtelTracing) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{t: t, ctx: ctx, cancel: cancel}, ctx
+}
+
+// SetLimit limits the number of goroutines running at once. It must be called before Go.
+// A negative value removes the limit.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs fn in a new goroutine inside a child span named name. When a limit is set, Go blocks
+// until a slot is free; the time spent waiting is recorded as the span's queue wait.
+func (g *Group) Go(name string, fn func(ctx context.Context) error) {
+	spawned := time.Now()
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+
+		if err := runGoroutine(g.ctx, g.t, name, spawned, false, fn); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Wait blocks until every goroutine started with Go has returned, then returns the first error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel(g.err)
+	return g.err
+}
@@ -0,0 +1,132 @@
+package _tracing
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// waitForSpan returns the ended span called name, waiting for goroutines to end it.
+func waitForSpan(t *testing.T, recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, span := range recorder.Ended() {
+			if span.Name() == name {
+				return span
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("span %s did not end", name)
+	return nil
+}
+
+func TestGoRunsInChildSpanAndRecoversPanics(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	ctx, parent := tr.StartSpan(context.Background(), "parent")
+	defer parent.End()
+
+	tr.Go(ctx, "child", func(context.Context) error { panic("kaboom") })
+
+	child := waitForSpan(t, recorder, "child")
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("goroutine span is not a child of the caller's span")
+	}
+	if child.Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error for the recovered panic", child.Status().Code)
+	}
+	if !hasAttributeKey(child, queueWaitKey) {
+		t.Errorf("attributes %v lack %s", child.Attributes(), queueWaitKey)
+	}
+}
+
+func TestGoDetachedStartsLinkedRootAndSurvivesCancel(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, parent := tr.StartSpan(ctx, "request")
+	parent.End()
+
+	ctxErr := make(chan error, 1)
+	tr.GoDetached(ctx, "job", func(ctx context.Context) error {
+		cancel()
+		ctxErr <- ctx.Err()
+		return nil
+	})
+
+	if err := <-ctxErr; err != nil {
+		t.Errorf("detached context error = %v, want it to outlive the caller", err)
+	}
+	job := waitForSpan(t, recorder, "job")
+	if job.Parent().IsValid() || job.SpanContext().TraceID() == parent.SpanContext().TraceID() {
+		t.Error("detached span is not the root of a new trace")
+	}
+	if links := job.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("links = %v, want the caller's span", links)
+	}
+}
+
+func TestGroupCancelsOnFirstError(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	errFirst := errors.New("first")
+	g, ctx := NewGroup(context.Background(), tr)
+
+	g.Go("fails", func(context.Context) error { return errFirst })
+	g.Go("waits", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if err := g.Wait(); err != errFirst {
+		t.Fatalf("Wait = %v, want %v", err, errFirst)
+	}
+	if !errors.Is(context.Cause(ctx), errFirst) {
+		t.Errorf("group context cause = %v, want %v", context.Cause(ctx), errFirst)
+	}
+	if got := len(recorder.Ended()); got != 2 {
+		t.Errorf("got %d spans, want one per goroutine", got)
+	}
+}
+
+func TestGroupSetLimit(t *testing.T) {
+	tr, _ := newRecordedTracing()
+	g, _ := NewGroup(context.Background(), tr)
+	g.SetLimit(2)
+
+	var running, peak atomic.Int32
+	for i := 0; i < 6; i++ {
+		g.Go("work", func(context.Context) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got := peak.Load(); got > 2 {
+		t.Errorf("%d goroutines ran at once, want at most 2", got)
+	}
+}
+
+func hasAttributeKey(span sdktrace.ReadOnlySpan, key string) bool {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return true
+		}
+	}
+	return false
+}
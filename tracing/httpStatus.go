@@ -0,0 +1,41 @@
+package _tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanStatusFromHTTPStatus maps an HTTP response status code to a span status following the
+// HTTP semantic conventions. Status codes outside 100-599 and 5xx responses are errors for
+// every span kind. 4xx responses are errors for client and internal spans, but are left unset
+// on server spans because the server handled the request correctly.
+func SpanStatusFromHTTPStatus(statusCode int, kind trace.SpanKind) (codes.Code, string) {
+	if statusCode < 100 || statusCode >= 600 {
+		return codes.Error, fmt.Sprintf("invalid HTTP status code %d", statusCode)
+	}
+	if statusCode >= 500 || (statusCode >= 400 && kind != trace.SpanKindServer) {
+		return codes.Error, http.StatusText(statusCode)
+	}
+	return codes.Unset, ""
+}
+
+// SetHTTPStatus records the HTTP response status code on the span and sets the span status
+// mapped by SpanStatusFromHTTPStatus.
+func (t *tracing) SetHTTPStatus(span trace.Span, statusCode int, kind trace.SpanKind) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+	if code, description := SpanStatusFromHTTPStatus(statusCode, kind); code != codes.Unset {
+		span.SetStatus(code, description)
+	}
+}
+
+// spanKind returns the kind of span when the SDK exposes it, and SpanKindInternal otherwise.
+func spanKind(span trace.Span) trace.SpanKind {
+	if s, ok := span.(interface{ SpanKind() trace.SpanKind }); ok {
+		return s.SpanKind()
+	}
+	return trace.SpanKindInternal
+}
@@ -0,0 +1,53 @@
+package _tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSpanStatusFromHTTPStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		kind   trace.SpanKind
+		want   codes.Code
+	}{
+		{200, trace.SpanKindServer, codes.Unset},
+		{304, trace.SpanKindClient, codes.Unset},
+		{404, trace.SpanKindServer, codes.Unset},
+		{404, trace.SpanKindClient, codes.Error},
+		{429, trace.SpanKindInternal, codes.Error},
+		{500, trace.SpanKindServer, codes.Error},
+		{503, trace.SpanKindClient, codes.Error},
+		{99, trace.SpanKindServer, codes.Error},
+		{600, trace.SpanKindServer, codes.Error},
+	}
+	for _, tt := range tests {
+		if got, _ := SpanStatusFromHTTPStatus(tt.status, tt.kind); got != tt.want {
+			t.Errorf("SpanStatusFromHTTPStatus(%d, %v) = %v, want %v", tt.status, tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestSetHTTPStatus(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+
+	_, span := tr.StartSpan(context.Background(), "GET /orders", trace.WithSpanKind(trace.SpanKindServer))
+	tr.SetHTTPStatus(span, 503, trace.SpanKindServer)
+	span.End()
+
+	ended := lastSpan(t, recorder)
+	if ended.Status().Code != codes.Error || ended.Status().Description != "Service Unavailable" {
+		t.Errorf("status = %+v, want Error: Service Unavailable", ended.Status())
+	}
+	found := false
+	for _, attr := range ended.Attributes() {
+		found = found || attr == semconv.HTTPResponseStatusCode(503)
+	}
+	if !found {
+		t.Errorf("attributes %v lack %s", ended.Attributes(), semconv.HTTPResponseStatusCodeKey)
+	}
+}
@@ -0,0 +1,105 @@
+package _tracing
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TruncatedSuffix is appended to attribute values that were shortened to fit the
+// configured attribute value length limit.
+const TruncatedSuffix = "...[truncated]"
+
+// limitAttributes truncates string and string slice attribute values that exceed
+// the configured attribute value length limit. A negative limit disables truncation.
+func (t *tracing) limitAttributes(attrs []attribute.KeyValue) []attribute.KeyValue {
+	if t.attributeValueLengthLimit < 0 {
+		return attrs
+	}
+
+	var limited []attribute.KeyValue
+	for i, attr := range attrs {
+		truncated, ok := truncateAttribute(attr, t.attributeValueLengthLimit)
+		if !ok {
+			continue
+		}
+		if limited == nil {
+			limited = append([]attribute.KeyValue(nil), attrs...)
+		}
+		limited[i] = truncated
+	}
+	if limited == nil {
+		return attrs
+	}
+	return limited
+}
+
+// limitEventOptions applies the attribute value length limit to the attributes
+// carried by the given event options.
+func (t *tracing) limitEventOptions(opts []trace.EventOption) []trace.EventOption {
+	if t.attributeValueLengthLimit < 0 || len(opts) == 0 {
+		return opts
+	}
+
+	cfg := trace.NewEventConfig(opts...)
+	limited := []trace.EventOption{
+		trace.WithAttributes(t.limitAttributes(cfg.Attributes())...),
+		trace.WithTimestamp(cfg.Timestamp()),
+	}
+	if cfg.StackTrace() {
+		limited = append(limited, trace.WithStackTrace(true))
+	}
+	return limited
+}
+
+// truncateAttribute returns the truncated attribute and whether it had to be shortened.
+func truncateAttribute(attr attribute.KeyValue, limit int) (attribute.KeyValue, bool) {
+	switch attr.Value.Type() {
+	case attribute.STRING:
+		if v := attr.Value.AsString(); len(v) > limit {
+			return attr.Key.String(TruncateString(v, limit)), true
+		}
+	case attribute.STRINGSLICE:
+		values := attr.Value.AsStringSlice()
+		truncated := false
+		for i, v := range values {
+			if len(v) > limit {
+				values[i] = TruncateString(v, limit)
+				truncated = true
+			}
+		}
+		if truncated {
+			return attr.Key.StringSlice(values), true
+		}
+	}
+	return attr, false
+}
+
+// TruncateString shortens s to at most limit bytes without splitting a UTF-8
+// character. When there is room, the result ends with TruncatedSuffix so that
+// readers can tell the value was cut.
+func TruncateString(s string, limit int) string {
+	if limit < 0 || len(s) <= limit {
+		return s
+	}
+
+	s = strings.ToValidUTF8(s, "")
+	if len(s) <= limit {
+		return s
+	}
+
+	if limit <= len(TruncatedSuffix) {
+		return truncateUTF8(s, limit)
+	}
+	return truncateUTF8(s, limit-len(TruncatedSuffix)) + TruncatedSuffix
+}
+
+// truncateUTF8 cuts a valid UTF-8 string at the last rune boundary that fits in limit bytes.
+func truncateUTF8(s string, limit int) string {
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
@@ -0,0 +1,201 @@
+package _tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		limit int
+		want  string
+	}{
+		{name: "shorter than limit", s: "hello", limit: 20, want: "hello"},
+		{name: "exactly the limit", s: strings.Repeat("a", 20), limit: 20, want: strings.Repeat("a", 20)},
+		{name: "negative limit", s: strings.Repeat("a", 50), limit: -1, want: strings.Repeat("a", 50)},
+		{name: "ascii", s: strings.Repeat("a", 30), limit: 20, want: strings.Repeat("a", 6) + TruncatedSuffix},
+		{name: "cut inside two-byte rune", s: strings.Repeat("é", 20), limit: 21, want: "ééé" + TruncatedSuffix},
+		{name: "cut inside four-byte rune", s: strings.Repeat("😀", 10), limit: 19, want: "😀" + TruncatedSuffix},
+		{name: "limit equals marker length", s: strings.Repeat("a", 30), limit: len(TruncatedSuffix), want: strings.Repeat("a", len(TruncatedSuffix))},
+		{name: "limit below marker length", s: strings.Repeat("a", 30), limit: 5, want: "aaaaa"},
+		{name: "limit below marker inside rune", s: "héllo world", limit: 2, want: "h"},
+		{name: "limit smaller than first rune", s: "😀 smile", limit: 3, want: ""},
+		{name: "zero limit", s: "hello", limit: 0, want: ""},
+		{name: "invalid utf8 dropped before cutting", s: "ab\xffc", limit: 3, want: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateString(tt.s, tt.limit)
+			if got != tt.want {
+				t.Errorf("TruncateString(%q, %d) = %q, want %q", tt.s, tt.limit, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("TruncateString(%q, %d) = %q is not valid UTF-8", tt.s, tt.limit, got)
+			}
+			if tt.limit >= 0 && len(got) > tt.limit {
+				t.Errorf("TruncateString(%q, %d) = %q is %d bytes", tt.s, tt.limit, got, len(got))
+			}
+		})
+	}
+}
+
+func TestLimitAttributes(t *testing.T) {
+	long := strings.Repeat("x", 30)
+	cut := strings.Repeat("x", 6) + TruncatedSuffix
+
+	tests := []struct {
+		name  string
+		limit int
+		attrs []attribute.KeyValue
+		want  []attribute.KeyValue
+	}{
+		{
+			name:  "short values untouched",
+			limit: 20,
+			attrs: []attribute.KeyValue{attribute.String("k", "short"), attribute.StringSlice("s", []string{"a", "b"})},
+			want:  []attribute.KeyValue{attribute.String("k", "short"), attribute.StringSlice("s", []string{"a", "b"})},
+		},
+		{
+			name:  "long string",
+			limit: 20,
+			attrs: []attribute.KeyValue{attribute.String("k", long)},
+			want:  []attribute.KeyValue{attribute.String("k", cut)},
+		},
+		{
+			name:  "string slice cuts only long elements",
+			limit: 20,
+			attrs: []attribute.KeyValue{attribute.StringSlice("s", []string{"short", long})},
+			want:  []attribute.KeyValue{attribute.StringSlice("s", []string{"short", cut})},
+		},
+		{
+			name:  "non-string values untouched",
+			limit: 1,
+			attrs: []attribute.KeyValue{attribute.Int64("n", 123456), attribute.Bool("b", true), attribute.Int64Slice("ns", []int64{1, 2})},
+			want:  []attribute.KeyValue{attribute.Int64("n", 123456), attribute.Bool("b", true), attribute.Int64Slice("ns", []int64{1, 2})},
+		},
+		{
+			name:  "disabled",
+			limit: -1,
+			attrs: []attribute.KeyValue{attribute.String("k", long), attribute.StringSlice("s", []string{long})},
+			want:  []attribute.KeyValue{attribute.String("k", long), attribute.StringSlice("s", []string{long})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, _ := newRecordedTracing(WithAttributeValueLengthLimit(tt.limit))
+			original := append([]attribute.KeyValue(nil), tt.attrs...)
+
+			got := tr.(*tracing).limitAttributes(tt.attrs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d attributes, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("attribute %d = %v, want %v", i, got[i].Value.Emit(), tt.want[i].Value.Emit())
+				}
+			}
+			for i := range original {
+				if tt.attrs[i] != original[i] {
+					t.Errorf("input attribute %d was modified", i)
+				}
+			}
+		})
+	}
+}
+
+func TestAttributeLimitAppliesToEventsAndErrors(t *testing.T) {
+	const limit = 20
+	long := strings.Repeat("é", 30)
+
+	tests := []struct {
+		name   string
+		record func(tr OtelTracing, ctx context.Context, span trace.Span)
+	}{
+		{
+			name: "AddEvent",
+			record: func(tr OtelTracing, ctx context.Context, span trace.Span) {
+				tr.AddEvent(ctx, span, "event", trace.WithAttributes(
+					attribute.String("message", long),
+					attribute.StringSlice("values", []string{"short", long}),
+				))
+			},
+		},
+		{
+			name: "RecordError",
+			record: func(tr OtelTracing, ctx context.Context, span trace.Span) {
+				tr.RecordError(ctx, span, errors.New(long))
+			},
+		},
+		{
+			name: "AddErrorAttributes",
+			record: func(tr OtelTracing, ctx context.Context, span trace.Span) {
+				tr.AddErrorAttributes(ctx, span, errors.New(long))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, recorder := newRecordedTracing(WithAttributeValueLengthLimit(limit))
+			ctx, span := tr.GetTracer().Start(context.Background(), "limited")
+			tt.record(tr, ctx, span)
+			span.End()
+
+			got := lastSpan(t, recorder)
+			if len(got.Events()) == 0 {
+				t.Fatal("no event recorded")
+			}
+			checkLimited(t, "span", got.Attributes(), limit)
+			for _, event := range got.Events() {
+				checkLimited(t, "event "+event.Name, event.Attributes, limit)
+			}
+		})
+	}
+}
+
+func TestRecordErrorTruncatesExceptionMessage(t *testing.T) {
+	tr, recorder := newRecordedTracing(WithAttributeValueLengthLimit(20))
+	ctx, span := tr.GetTracer().Start(context.Background(), "limited")
+	tr.RecordError(ctx, span, errors.New(strings.Repeat("x", 30)))
+	span.End()
+
+	want := strings.Repeat("x", 6) + TruncatedSuffix
+	for _, event := range lastSpan(t, recorder).Events() {
+		if event.Name != semconv.ExceptionEventName {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			if attr.Key == semconv.ExceptionMessageKey && attr.Value.AsString() != want {
+				t.Errorf("exception.message = %q, want %q", attr.Value.AsString(), want)
+			}
+		}
+		return
+	}
+	t.Error("no exception event recorded")
+}
+
+func checkLimited(t *testing.T, where string, attrs []attribute.KeyValue, limit int) {
+	t.Helper()
+	for _, attr := range attrs {
+		values := attr.Value.AsStringSlice()
+		if attr.Value.Type() == attribute.STRING {
+			values = []string{attr.Value.AsString()}
+		}
+		for _, v := range values {
+			if len(v) > limit || !utf8.ValidString(v) {
+				t.Errorf("%s attribute %s = %q exceeds %d bytes or is not valid UTF-8", where, attr.Key, v, limit)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package _tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartSpanWithLinks starts a span linked to each valid span context in links. It is meant for
+// batch and fan-in work where one span processes items that belong to several traces.
+func (t *tracing) StartSpanWithLinks(ctx context.Context, spanName string, links []trace.SpanContext, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	spanLinks := make([]trace.Link, 0, len(links))
+	for _, sc := range links {
+		if sc.IsValid() {
+			spanLinks = append(spanLinks, trace.Link{SpanContext: sc})
+		}
+	}
+	if len(spanLinks) > 0 {
+		opts = append(opts[:len(opts):len(opts)], trace.WithLinks(spanLinks...))
+	}
+	return t.StartSpan(ctx, spanName, opts...)
+}
+
+// AddLink links an already started span to the given span context with optional attributes.
+// Invalid span contexts are ignored.
+func (t *tracing) AddLink(span trace.Span, sc trace.SpanContext, attrs ...attribute.KeyValue) {
+	if !sc.IsValid() || !span.IsRecording() {
+		return
+	}
+	span.AddLink(trace.Link{SpanContext: sc, Attributes: t.limitAttributes(attrs)})
+}
+
+// ExtractLink returns the remote span context carried by carrier, using the configured
+// propagator. The result is invalid when the carrier holds no trace context.
+func (t *tracing) ExtractLink(ctx context.Context, carrier propagation.TextMapCarrier) trace.SpanContext {
+	return trace.SpanContextFromContext(t.propagator.Extract(ctx, carrier))
+}
+
+// ExtractLinks returns the valid span contexts carried by carriers, for example the headers of
+// every message in a batch, ready to pass to StartSpanWithLinks.
+func (t *tracing) ExtractLinks(ctx context.Context, carriers ...propagation.TextMapCarrier) []trace.SpanContext {
+	links := make([]trace.SpanContext, 0, len(carriers))
+	for _, carrier := range carriers {
+		if sc := t.ExtractLink(ctx, carrier); sc.IsValid() {
+			links = append(links, sc)
+		}
+	}
+	return links
+}
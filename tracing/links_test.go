@@ -0,0 +1,53 @@
+package _tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestStartSpanWithLinksSkipsInvalidContexts(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	_, producer := tr.StartSpan(context.Background(), "producer")
+	producer.End()
+
+	_, span := tr.StartSpanWithLinks(context.Background(), "batch", []trace.SpanContext{producer.SpanContext(), {}})
+	span.End()
+
+	links := lastSpan(t, recorder).Links()
+	if len(links) != 1 || links[0].SpanContext.SpanID() != producer.SpanContext().SpanID() {
+		t.Errorf("links = %v, want only the producer span", links)
+	}
+}
+
+func TestAddLink(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	_, other := tr.StartSpan(context.Background(), "other")
+	other.End()
+
+	_, span := tr.StartSpan(context.Background(), "consumer")
+	tr.AddLink(span, other.SpanContext(), attribute.String("link.reason", "retry"))
+	tr.AddLink(span, trace.SpanContext{})
+	span.End()
+
+	links := lastSpan(t, recorder).Links()
+	if len(links) != 1 || len(links[0].Attributes) != 1 || links[0].Attributes[0] != attribute.String("link.reason", "retry") {
+		t.Errorf("links = %v, want one link with its attribute", links)
+	}
+}
+
+func TestExtractLinksFromCarriers(t *testing.T) {
+	tr, _ := newRecordedTracing()
+	ctx, span := tr.StartSpan(context.Background(), "producer")
+	defer span.End()
+
+	carrier := propagation.MapCarrier{}
+	tr.Inject(ctx, carrier)
+	links := tr.ExtractLinks(context.Background(), carrier, propagation.MapCarrier{})
+	if len(links) != 1 || links[0].SpanID() != span.SpanContext().SpanID() || !links[0].IsRemote() {
+		t.Errorf("links = %v, want the remote producer span only", links)
+	}
+}
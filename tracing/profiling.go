@@ -0,0 +1,148 @@
+package _tracing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime/pprof"
+	"time"
+
+	"otel-library/internal/cpuprofile"
+	"otel-library/internal/goroutine"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Profiler label keys applied by WithProfilerLabels. They can be used as tag filters, e.g.
+// `go tool pprof -tagfocus=span.name=checkout cpu.pprof`.
+const (
+	ProfilerLabelSpanName = "span.name"
+	ProfilerLabelSpanID   = "span.id"
+	ProfilerLabelRoute    = "http.route"
+)
+
+// ProfilerLabels selects the optional pprof labels applied to goroutines running a span. The
+// span name is always applied.
+type ProfilerLabels struct {
+	// SpanID adds the span ID. Every span then produces distinct samples, which makes
+	// profiles noticeably larger.
+	SpanID bool
+	// Route adds the http.route attribute given when the span is started.
+	Route bool
+}
+
+// WithProfilerLabels makes StartSpan apply pprof labels to the calling goroutine until the span
+// ends, so that CPU profile samples can be tied back to spans. The previous labels are restored
+// by End when it is called on the goroutine that started the span; ending the span on another
+// goroutine leaves the labels of both goroutines untouched.
+func WithProfilerLabels(labels ProfilerLabels) Option {
+	return func(t *tracing) {
+		t.profilerLabels = &labels
+	}
+}
+
+// labeledSpan restores the previous pprof labels of the goroutine that started the span when
+// the span ends on that goroutine. The ID of that goroutine is looked up once, at start.
+type labeledSpan struct {
+	trace.Span
+	restore   context.Context
+	goroutine uint64
+}
+
+func (s *labeledSpan) End(options ...trace.SpanEndOption) {
+	s.Span.End(options...)
+	if s.goroutine != 0 && goroutine.ID() == s.goroutine {
+		pprof.SetGoroutineLabels(s.restore)
+	}
+}
+
+// SpanKind exposes the kind of the wrapped span to spanKind.
+func (s *labeledSpan) SpanKind() trace.SpanKind {
+	return spanKind(s.Span)
+}
+
+// applyProfilerLabels labels the current goroutine for span. parent is the context the span was
+// started from; its labels are restored when the span ends.
+func (t *tracing) applyProfilerLabels(parent, ctx context.Context, spanName string, span trace.Span, opts []trace.SpanStartOption) (context.Context, trace.Span) {
+	labels := []string{ProfilerLabelSpanName, spanName}
+	if t.profilerLabels.SpanID {
+		labels = append(labels, ProfilerLabelSpanID, span.SpanContext().SpanID().String())
+	}
+	if t.profilerLabels.Route {
+		cfg := trace.NewSpanStartConfig(opts...)
+		for _, attr := range cfg.Attributes() {
+			if attr.Key == semconv.HTTPRouteKey {
+				labels = append(labels, ProfilerLabelRoute, attr.Value.Emit())
+			}
+		}
+	}
+
+	ctx = pprof.WithLabels(ctx, pprof.Labels(labels...))
+	pprof.SetGoroutineLabels(ctx)
+	labeled := &labeledSpan{Span: span, restore: parent, goroutine: goroutine.ID()}
+	return trace.ContextWithSpan(ctx, labeled), labeled
+}
+
+// profileTopFunctions is the number of functions listed in a "profile.cpu" event.
+const profileTopFunctions = 5
+
+// ProfileCPU captures a CPU profile of the whole process for d, or until ctx is done, and
+// writes it to w in pprof format. A "profile.cpu" event summarising the capture is added to
+// span: its duration and size, the number of samples and the functions with the most samples.
+// When profiler labels are enabled only the samples labeled for the span are counted, and the
+// event also holds the tag filter that selects them. Only one CPU profile can run at a time;
+// otherwise an error is returned.
+func (t *tracing) ProfileCPU(ctx context.Context, span trace.Span, d time.Duration, w io.Writer) error {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(d)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+	pprof.StopCPUProfile()
+	elapsed := time.Since(start)
+
+	attrs := []attribute.KeyValue{
+		ToAttribute("profile.duration", elapsed),
+		attribute.Int("profile.size", buf.Len()),
+	}
+	var labelKey, labelValue string
+	if t.profilerLabels != nil && t.profilerLabels.SpanID {
+		labelKey, labelValue = ProfilerLabelSpanID, span.SpanContext().SpanID().String()
+	} else if t.profilerLabels != nil {
+		labelValue, _ = pprof.Label(ctx, ProfilerLabelSpanName)
+		if labelValue != "" {
+			labelKey = ProfilerLabelSpanName
+		}
+	}
+	if labelKey != "" {
+		attrs = append(attrs, attribute.String("profile.tagfocus", labelKey+"="+labelValue))
+	}
+
+	if summary, err := cpuprofile.Summarize(buf.Bytes(), labelKey, labelValue, profileTopFunctions); err == nil {
+		functions := make([]string, len(summary.Top))
+		samples := make([]int64, len(summary.Top))
+		for i, fn := range summary.Top {
+			functions[i], samples[i] = fn.Name, fn.Samples
+		}
+		attrs = append(attrs,
+			attribute.Int64("profile.samples", summary.Samples),
+			attribute.StringSlice("profile.top_functions", functions),
+			attribute.Int64Slice("profile.top_function_samples", samples),
+		)
+	} else {
+		t.logger(ctx, span).Warnf("cannot summarise CPU profile: %v", err)
+	}
+	t.AddEvent(ctx, span, "profile.cpu", trace.WithAttributes(attrs...))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,160 @@
+package _tracing
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"otel-library/internal/cpuprofile"
+	apw_logging "otel-library/logs"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// labeled reports whether any goroutine currently carries the pprof label key=value.
+func labeled(t *testing.T, key, value string) bool {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Contains(buf.String(), `"`+key+`":"`+value+`"`)
+}
+
+func newProfiledTracing(labels ProfilerLabels) OtelTracing {
+	tp := sdktrace.NewTracerProvider()
+	return NewTracing(tp.Tracer("test"), apw_logging.NewNoopLogging(), WithProfilerLabels(labels))
+}
+
+func TestProfilerLabelsAppliedUntilEnd(t *testing.T) {
+	tr := newProfiledTracing(ProfilerLabels{SpanID: true, Route: true})
+
+	ctx, span := tr.StartSpan(context.Background(), "labeled-work", trace.WithAttributes(semconv.HTTPRoute("/orders/{id}")))
+	spanID := span.SpanContext().SpanID().String()
+	for key, value := range map[string]string{
+		ProfilerLabelSpanName: "labeled-work",
+		ProfilerLabelSpanID:   spanID,
+		ProfilerLabelRoute:    "/orders/{id}",
+	} {
+		if got, ok := pprof.Label(ctx, key); !ok || got != value {
+			t.Errorf("context label %s = %q, want %q", key, got, value)
+		}
+		if !labeled(t, key, value) {
+			t.Errorf("goroutine lacks label %s=%s", key, value)
+		}
+	}
+
+	span.End()
+	if labeled(t, ProfilerLabelSpanName, "labeled-work") {
+		t.Error("goroutine labels were not restored by End")
+	}
+}
+
+func TestProfilerLabelsKeptWhenEndedOnAnotherGoroutine(t *testing.T) {
+	tr := newProfiledTracing(ProfilerLabels{})
+	defer pprof.SetGoroutineLabels(context.Background())
+
+	_, span := tr.StartSpan(context.Background(), "handed-off")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels("worker", "ender")))
+		span.End()
+		if !labeled(t, "worker", "ender") {
+			t.Error("End replaced the labels of the goroutine it ran on")
+		}
+	}()
+	<-done
+
+	if !labeled(t, ProfilerLabelSpanName, "handed-off") {
+		t.Error("End on another goroutine cleared the starting goroutine's labels")
+	}
+}
+
+var spinSink uint64
+
+// spinUntil burns CPU until stop is set, so that it shows up in CPU profiles.
+//
+//go:noinline
+func spinUntil(stop *atomic.Bool) {
+	x := uint64(1)
+	for !stop.Load() {
+		for i := 0; i < 1000; i++ {
+			x = x*6364136223846793005 + 1442695040888963407
+		}
+	}
+	atomic.AddUint64(&spinSink, x)
+}
+
+// spinUnlabeled is a copy of spinUntil, run without the span's labels.
+//
+//go:noinline
+func spinUnlabeled(stop *atomic.Bool) {
+	x := uint64(1)
+	for !stop.Load() {
+		for i := 0; i < 1000; i++ {
+			x = x*6364136223846793005 + 1442695040888963407
+		}
+	}
+	atomic.AddUint64(&spinSink, x)
+}
+
+func TestProfileCPUSummarisesLabeledSamples(t *testing.T) {
+	tr, recorder := newRecordedTracing(WithProfilerLabels(ProfilerLabels{SpanID: true}))
+	defer pprof.SetGoroutineLabels(context.Background())
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); spinUnlabeled(&stop) }()
+
+	ctx, span := tr.StartSpan(context.Background(), "busy")
+	// Goroutines inherit the labels of the goroutine that starts them.
+	go func() { defer wg.Done(); spinUntil(&stop) }()
+	var out bytes.Buffer
+	err := tr.ProfileCPU(ctx, span, 500*time.Millisecond, &out)
+	stop.Store(true)
+	wg.Wait()
+	span.End()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event sdktrace.Event
+	for _, e := range lastSpan(t, recorder).Events() {
+		if e.Name == "profile.cpu" {
+			event = e
+		}
+	}
+	attrs := attribute.NewSet(event.Attributes...)
+	if v, _ := attrs.Value("profile.tagfocus"); v.AsString() != ProfilerLabelSpanID+"="+span.SpanContext().SpanID().String() {
+		t.Errorf("profile.tagfocus = %q", v.AsString())
+	}
+	if v, _ := attrs.Value("profile.samples"); v.AsInt64() <= 0 {
+		t.Fatalf("profile.samples = %d, want the span's samples", v.AsInt64())
+	}
+	functions, _ := attrs.Value("profile.top_functions")
+	samples, _ := attrs.Value("profile.top_function_samples")
+	if len(functions.AsStringSlice()) == 0 || len(functions.AsStringSlice()) != len(samples.AsInt64Slice()) {
+		t.Fatalf("top functions %v and samples %v do not match", functions.AsStringSlice(), samples.AsInt64Slice())
+	}
+	got := strings.Join(functions.AsStringSlice(), ",")
+	if !strings.Contains(got, "tracing.spinUntil") {
+		t.Errorf("profile.top_functions = %s, want the span's spinUntil", got)
+	}
+	if strings.Contains(got, "tracing.spinUnlabeled") {
+		t.Errorf("profile.top_functions = %s, includes samples of another goroutine", got)
+	}
+
+	if _, err := cpuprofile.Summarize(out.Bytes(), "", "", 1); err != nil {
+		t.Errorf("written profile does not parse: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package _tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// MessageHeader is a Kafka-style message header with a byte slice value.
+type MessageHeader struct {
+	Key   string
+	Value []byte
+}
+
+// MessageHeadersCarrier adapts a slice of message headers to propagation.TextMapCarrier.
+// Set replaces an existing header with the same key or appends a new one.
+type MessageHeadersCarrier struct {
+	Headers *[]MessageHeader
+}
+
+// Get returns the value of the first header named key.
+func (c MessageHeadersCarrier) Get(key string) string {
+	if c.Headers == nil {
+		return ""
+	}
+	for _, h := range *c.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set stores value under key.
+func (c MessageHeadersCarrier) Set(key, value string) {
+	if c.Headers == nil {
+		return
+	}
+	for i, h := range *c.Headers {
+		if h.Key == key {
+			(*c.Headers)[i].Value = []byte(value)
+			return
+		}
+	}
+	*c.Headers = append(*c.Headers, MessageHeader{Key: key, Value: []byte(value)})
+}
+
+// Keys lists the header keys.
+func (c MessageHeadersCarrier) Keys() []string {
+	if c.Headers == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(*c.Headers))
+	for _, h := range *c.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// EnvCarrier carries trace context in environment variables such as TRACEPARENT, TRACESTATE
+// and BAGGAGE, to hand context to child processes. Set writes KEY=value entries to Env, which
+// can be assigned to exec.Cmd.Env; Get only reads Env. Use ProcessEnvCarrier to extract the
+// context this process was started with.
+type EnvCarrier struct {
+	Env []string
+}
+
+// ProcessEnvCarrier returns an EnvCarrier holding a copy of the current process environment.
+func ProcessEnvCarrier() *EnvCarrier {
+	return &EnvCarrier{Env: os.Environ()}
+}
+
+// Get returns the value of the environment variable for key in Env.
+func (c *EnvCarrier) Get(key string) string {
+	name := envName(key)
+	for _, entry := range c.Env {
+		if k, v, ok := strings.Cut(entry, "="); ok && k == name {
+			return v
+		}
+	}
+	return ""
+}
+
+// Set stores value in Env under the environment variable name for key.
+func (c *EnvCarrier) Set(key, value string) {
+	name := envName(key)
+	entry := name + "=" + value
+	for i, existing := range c.Env {
+		if strings.HasPrefix(existing, name+"=") {
+			c.Env[i] = entry
+			return
+		}
+	}
+	c.Env = append(c.Env, entry)
+}
+
+// Keys lists the variable names in Env.
+func (c *EnvCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.Env))
+	for _, entry := range c.Env {
+		if k, _, ok := strings.Cut(entry, "="); ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// envName converts a propagation key such as "traceparent" into its environment variable name.
+func envName(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// carrierOf adapts the supported carrier types to propagation.TextMapCarrier.
+func carrierOf(carrier any) (propagation.TextMapCarrier, bool) {
+	switch c := carrier.(type) {
+	case propagation.TextMapCarrier:
+		return c, true
+	case http.Header:
+		return propagation.HeaderCarrier(c), true
+	case map[string]string:
+		return propagation.MapCarrier(c), true
+	case *[]MessageHeader:
+		return MessageHeadersCarrier{Headers: c}, true
+	}
+	return nil, false
+}
+
+// Inject writes the trace context and baggage of ctx into carrier using the configured
+// propagator. Supported carriers are http.Header, map[string]string, *[]MessageHeader,
+// *EnvCarrier and any propagation.TextMapCarrier. A nil http.Header or map cannot be written
+// to and is left untouched.
+func (t *tracing) Inject(ctx context.Context, carrier any) {
+	if isNilMap(carrier) {
+		t.l.Warnf("tracing: cannot inject context into nil %T", carrier)
+		return
+	}
+	c, ok := carrierOf(carrier)
+	if !ok {
+		t.l.Warnf("tracing: cannot inject context into unsupported carrier %T", carrier)
+		return
+	}
+	t.propagator.Inject(ctx, c)
+}
+
+// isNilMap reports whether carrier is a nil map carrier, which would panic on Set.
+func isNilMap(carrier any) bool {
+	switch c := carrier.(type) {
+	case http.Header:
+		return c == nil
+	case map[string]string:
+		return c == nil
+	case propagation.HeaderCarrier:
+		return c == nil
+	case propagation.MapCarrier:
+		return c == nil
+	}
+	return false
+}
+
+// Extract returns a copy of ctx holding the remote trace context and baggage read from
+// carrier using the configured propagator. It accepts the same carriers as Inject.
+func (t *tracing) Extract(ctx context.Context, carrier any) context.Context {
+	c, ok := carrierOf(carrier)
+	if !ok {
+		t.l.Warnf("tracing: cannot extract context from unsupported carrier %T", carrier)
+		return ctx
+	}
+	return t.propagator.Extract(ctx, c)
+}
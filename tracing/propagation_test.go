@@ -0,0 +1,62 @@
+package _tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	apw_logging "otel-library/logs"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var remoteSpanContext = trace.NewSpanContext(trace.SpanContextConfig{
+	TraceID:    trace.TraceID{0x01},
+	SpanID:     trace.SpanID{0x02},
+	TraceFlags: trace.FlagsSampled,
+	Remote:     true,
+})
+
+func TestWithPropagatorNilKeepsDefault(t *testing.T) {
+	tr := NewTracing(nil, apw_logging.NewNoopLogging(), WithPropagator(nil))
+	ctx := trace.ContextWithSpanContext(context.Background(), remoteSpanContext)
+
+	carrier := map[string]string{}
+	tr.Inject(ctx, carrier)
+	if carrier["traceparent"] == "" {
+		t.Fatalf("traceparent not injected: %v", carrier)
+	}
+}
+
+func TestInjectIntoNilMapsDoesNotPanic(t *testing.T) {
+	tr := NewTracing(nil, apw_logging.NewNoopLogging())
+	ctx := trace.ContextWithSpanContext(context.Background(), remoteSpanContext)
+
+	var m map[string]string
+	var h http.Header
+	tr.Inject(ctx, m)
+	tr.Inject(ctx, h)
+}
+
+func TestEnvCarrierRoundTrip(t *testing.T) {
+	tr := NewTracing(nil, apw_logging.NewNoopLogging())
+	ctx := trace.ContextWithSpanContext(context.Background(), remoteSpanContext)
+
+	carrier := &EnvCarrier{}
+	tr.Inject(ctx, carrier)
+	got := trace.SpanContextFromContext(tr.Extract(context.Background(), carrier))
+	if got.TraceID() != remoteSpanContext.TraceID() || got.SpanID() != remoteSpanContext.SpanID() {
+		t.Fatalf("extracted %v, want %v", got, remoteSpanContext)
+	}
+}
+
+func TestEnvCarrierIgnoresProcessEnvironment(t *testing.T) {
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+
+	if got := (&EnvCarrier{}).Get("traceparent"); got != "" {
+		t.Errorf("EnvCarrier.Get = %q, want empty", got)
+	}
+	if got := ProcessEnvCarrier().Get("traceparent"); got == "" {
+		t.Error("ProcessEnvCarrier().Get returned empty, want the process TRACEPARENT")
+	}
+}
@@ -0,0 +1,74 @@
+package _tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Run starts a span named name, calls fn with the span's context and ends the span when fn
+// returns. The span status is set from the returned error, and a panic in fn is recorded as an
+// exception before it is re-raised.
+func (t *tracing) Run(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...trace.SpanStartOption) error {
+	return runSpan(ctx, t, name, 2, fn, opts...)
+}
+
+// RunWithResult behaves like OtelTracing.Run for functions that also return a value.
+func RunWithResult[T any](ctx context.Context, t OtelTracing, name string, fn func(ctx context.Context) (T, error), opts ...trace.SpanStartOption) (T, error) {
+	var result T
+	err := runSpan(ctx, t, name, 2, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	}, opts...)
+	return result, err
+}
+
+// runSpan wraps fn in a span. skip is the number of stack frames from runSpan up to the user
+// code that should be reported in the code.* attributes.
+func runSpan(ctx context.Context, t OtelTracing, name string, skip int, fn func(ctx context.Context) error, opts ...trace.SpanStartOption) (err error) {
+	opts = append(opts[:len(opts):len(opts)], trace.WithAttributes(lookupCaller(skip).attrs...))
+	ctx, span := t.StartSpan(ctx, name, opts...)
+	defer t.EndSpan(span)
+
+	defer func() {
+		if r := recover(); r != nil {
+			panicErr := fmt.Errorf("panic: %v", r)
+			span.RecordError(panicErr, trace.WithStackTrace(true))
+			t.SetStatus(span, codes.Error, panicErr.Error())
+			panic(r)
+		}
+	}()
+
+	err = fn(ctx)
+	setStatusFromError(ctx, t, span, err)
+	return err
+}
+
+// setStatusFromError marks the span as OK when err is nil, unless a status was already set on
+// it, such as an Error from SetHTTPStatus. An *errs.ErrorService with a status below 400 is
+// recorded as a handled error; any other error through RecordError.
+func setStatusFromError(ctx context.Context, t OtelTracing, span trace.Span, err error) {
+	if err == nil {
+		if statusUnset(span) {
+			t.SetStatus(span, codes.Ok, "")
+		}
+		return
+	}
+
+	if errService := asErrorService(err); errService != nil && errService.StatusCode < 400 {
+		t.AddErrorAttributes(ctx, span, err)
+		return
+	}
+	t.RecordError(ctx, span, err)
+}
+
+// statusUnset reports whether no status has been set on span yet. Spans that do not expose
+// their status are treated as set, so that OK never overrides an earlier status.
+func statusUnset(span trace.Span) bool {
+	ro, ok := span.(interface{ Status() sdktrace.Status })
+	return ok && ro.Status().Code == codes.Unset
+}
@@ -0,0 +1,131 @@
+package _tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"otel-library/errs"
+	apw_logging "otel-library/logs"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newRecordedTracing returns an OtelTracing whose ended spans are kept by the returned recorder.
+func newRecordedTracing(opts ...Option) (OtelTracing, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	opts = append([]Option{WithTracerProvider(tp)}, opts...)
+	return NewTracing(tp.Tracer("test"), apw_logging.NewNoopLogging(), opts...), recorder
+}
+
+func lastSpan(t *testing.T, recorder *tracetest.SpanRecorder) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) == 0 {
+		t.Fatal("no span ended")
+	}
+	return spans[len(spans)-1]
+}
+
+func TestRunSetsStatusFromError(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name   string
+		err    error
+		status codes.Code
+		event  string
+	}{
+		{"ok", nil, codes.Ok, ""},
+		{"error", errBoom, codes.Error, semconv.ExceptionEventName},
+		{"handled ErrorService", &errs.ErrorService{StatusCode: http.StatusNoContent, ErrorCode: "EMPTY"}, codes.Unset, "ErrorHandled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inner context.Context
+			err := tr.Run(context.Background(), tt.name, func(ctx context.Context) error {
+				inner = ctx
+				return tt.err
+			})
+			if err != tt.err {
+				t.Fatalf("Run returned %v, want %v", err, tt.err)
+			}
+
+			span := lastSpan(t, recorder)
+			if span.Name() != tt.name || span.Status().Code != tt.status {
+				t.Errorf("span %q status = %v, want %q %v", span.Name(), span.Status().Code, tt.name, tt.status)
+			}
+			var event string
+			for _, e := range span.Events() {
+				event += e.Name
+			}
+			if event != tt.event {
+				t.Errorf("got events %q, want %q", event, tt.event)
+			}
+			if got := spanFromContextID(inner); got != span.SpanContext().SpanID().String() {
+				t.Error("fn did not run in the span's context")
+			}
+		})
+	}
+}
+
+func TestRunRecordsPanicAndRepanics(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "kaboom" {
+				t.Errorf("recovered %v, want the original panic value", r)
+			}
+		}()
+		tr.Run(context.Background(), "panics", func(context.Context) error { panic("kaboom") })
+	}()
+
+	span := lastSpan(t, recorder)
+	if span.Status().Code != codes.Error || span.Status().Description != "panic: kaboom" {
+		t.Errorf("status = %+v, want an error describing the panic", span.Status())
+	}
+	if events := span.Events(); len(events) != 1 || events[0].Name != semconv.ExceptionEventName {
+		t.Errorf("events = %v, want one exception event", events)
+	}
+}
+
+func TestRunWithResultReturnsValueAndCaller(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+
+	got, err := RunWithResult(context.Background(), tr, "compute", func(context.Context) (int, error) { return 42, nil })
+	if err != nil || got != 42 {
+		t.Fatalf("RunWithResult = %d, %v; want 42, nil", got, err)
+	}
+
+	span := lastSpan(t, recorder)
+	for _, attr := range span.Attributes() {
+		if attr.Key == semconv.CodeFunctionKey && attr.Value.AsString() != "TestRunWithResultReturnsValueAndCaller" {
+			t.Errorf("%s = %q, want the test function", semconv.CodeFunctionKey, attr.Value.AsString())
+		}
+	}
+}
+
+// spanFromContextID returns the span ID of the span in ctx.
+func spanFromContextID(ctx context.Context) string {
+	return trace.SpanContextFromContext(ctx).SpanID().String()
+}
+
+func TestRunKeepsStatusSetInsideFn(t *testing.T) {
+	tr, recorder := newRecordedTracing()
+
+	tr.Run(context.Background(), "server error", func(ctx context.Context) error {
+		tr.SetHTTPStatus(trace.SpanFromContext(ctx), http.StatusInternalServerError, trace.SpanKindServer)
+		return nil
+	})
+	if got := lastSpan(t, recorder).Status().Code; got != codes.Error {
+		t.Errorf("status = %v, want the Error set by SetHTTPStatus", got)
+	}
+}
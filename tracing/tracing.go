@@ -2,46 +2,141 @@ package _tracing
 
 import (
 	"context"
-	"fmt"
+	"io"
 	"net/http"
+	"time"
 
-	"otel-library/errs"
+	"otel-library/internal/baggageattr"
 	_logging "otel-library/logs"
 
+	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type OtelTracing interface {
 	StartSpan(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span)
 	EndSpan(span trace.Span)
+	EndSpanWithError(span trace.Span, err *error, opts ...trace.SpanEndOption)
+	EndSpanWithStatus(span trace.Span, statusCode *int, opts ...trace.SpanEndOption)
 	SetStatus(span trace.Span, code codes.Code, description string)
 	AddAttribute(span trace.Span, key string, value any)
 	AddEvent(ctx context.Context, span trace.Span, eventName string, opts ...trace.EventOption)
 	SetOKStatus(span trace.Span, description string, attrs ...attribute.KeyValue)
 	SetNoContentStatus(span trace.Span, description string, attrs ...attribute.KeyValue)
 	GetTracer() trace.Tracer
-	RecordError(ctx context.Context, span trace.Span, err *errs.ErrorService)
-	AddErrorAttributes(ctx context.Context, span trace.Span, err *errs.ErrorService)
+	RecordError(ctx context.Context, span trace.Span, err error)
+	AddErrorAttributes(ctx context.Context, span trace.Span, err error)
 	AddAttributes(span trace.Span, err error, attrs ...attribute.KeyValue)
+	AddAttributesFrom(span trace.Span, v any)
+	SetHTTPStatus(span trace.Span, statusCode int, kind trace.SpanKind)
+	StartSpanWithLinks(ctx context.Context, spanName string, links []trace.SpanContext, opts ...trace.SpanStartOption) (context.Context, trace.Span)
+	AddLink(span trace.Span, sc trace.SpanContext, attrs ...attribute.KeyValue)
+	ExtractLink(ctx context.Context, carrier propagation.TextMapCarrier) trace.SpanContext
+	ExtractLinks(ctx context.Context, carriers ...propagation.TextMapCarrier) []trace.SpanContext
+	Inject(ctx context.Context, carrier any)
+	Extract(ctx context.Context, carrier any) context.Context
+	SetBaggage(ctx context.Context, key, value string) (context.Context, error)
+	GetBaggage(ctx context.Context, key string) string
+	RemoveBaggage(ctx context.Context, key string) context.Context
+	StartSpanAuto(ctx context.Context, opts ...trace.SpanStartOption) (context.Context, trace.Span)
+	Go(ctx context.Context, name string, fn func(ctx context.Context) error)
+	GoDetached(ctx context.Context, name string, fn func(ctx context.Context) error)
+	Tracer(scopeName, version string) OtelTracing
+	Run(ctx context.Context, name string, fn func(ctx context.Context) error, opts ...trace.SpanStartOption) error
+	ProfileCPU(ctx context.Context, span trace.Span, d time.Duration, w io.Writer) error
 }
 
 type tracing struct {
-	tracer trace.Tracer
-	l      _logging.OtelLogging
+	tracer                    trace.Tracer
+	provider                  trace.TracerProvider
+	propagator                propagation.TextMapPropagator
+	l                         _logging.OtelLogging
+	attributeValueLengthLimit int
+	baggageKeys               []string
+	baggageMaxEntries         int
+	baggageMaxBytes           int
+	profilerLabels            *ProfilerLabels
+}
+
+// Option configures optional behaviour of the OtelTracing returned by NewTracing.
+type Option func(*tracing)
+
+// WithAttributeValueLengthLimit truncates string attribute values set through the
+// tracing helpers to at most limit bytes. A negative limit disables truncation.
+func WithAttributeValueLengthLimit(limit int) Option {
+	return func(t *tracing) {
+		t.attributeValueLengthLimit = limit
+	}
+}
+
+// WithTracerProvider sets the provider used to create scoped tracers. Without it the global
+// TracerProvider is used.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(t *tracing) {
+		t.provider = provider
+	}
+}
+
+// WithPropagator sets the propagator used to inject and extract trace context. The default
+// propagates W3C trace context and baggage; a nil propagator keeps the default.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(t *tracing) {
+		if propagator != nil {
+			t.propagator = propagator
+		}
+	}
+}
+
+// DefaultPropagator returns the W3C trace context and baggage propagator used when none is configured.
+func DefaultPropagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
 }
 
 // NewTracing initializes a new OtelTracing instance with the given Tracer.
-func NewTracing(tracer trace.Tracer, l _logging.OtelLogging) OtelTracing {
+func NewTracing(tracer trace.Tracer, l _logging.OtelLogging, opts ...Option) OtelTracing {
+	t := &tracing{
+		tracer:                    tracer,
+		l:                         l,
+		propagator:                DefaultPropagator(),
+		attributeValueLengthLimit: -1,
+		baggageMaxEntries:         DefaultBaggageMaxEntries,
+		baggageMaxBytes:           DefaultBaggageMaxBytes,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Tracer returns an OtelTracing that shares this instance's provider and settings but records
+// spans under the given instrumentation scope name and version.
+func (t *tracing) Tracer(scopeName, version string) OtelTracing {
+	provider := t.provider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
 
-	return &tracing{tracer: tracer, l: l}
+	scoped := *t
+	scoped.tracer = provider.Tracer(scopeName, trace.WithInstrumentationVersion(version))
+	return &scoped
 }
 
-// StartSpan creates a new span with the given name and options.
+// StartSpan creates a new span with the given name and options. Promoted baggage keys present
+// in ctx are added as span attributes, and with WithProfilerLabels the calling goroutine is
+// labeled until the span ends.
 func (t *tracing) StartSpan(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
-	return t.tracer.Start(ctx, spanName, opts...)
+	if attrs := baggageattr.Attributes(ctx, t.baggageKeys); len(attrs) > 0 {
+		opts = append(opts[:len(opts):len(opts)], trace.WithAttributes(t.limitAttributes(attrs)...))
+	}
+	spanCtx, span := t.tracer.Start(ctx, spanName, opts...)
+	if t.profilerLabels != nil {
+		return t.applyProfilerLabels(ctx, spanCtx, spanName, span, opts)
+	}
+	return spanCtx, span
 }
 
 // EndSpan ends the given span.
@@ -54,37 +149,35 @@ func (t *tracing) SetStatus(span trace.Span, code codes.Code, description string
 	span.SetStatus(code, description)
 }
 
-// AddAttributes adds multiple attributes to the given span.
+// AddAttribute adds a single attribute to the given span, converting value with ToAttribute.
 func (t *tracing) AddAttribute(span trace.Span, key string, value any) {
-	switch v := value.(type) {
-	case string:
-		span.SetAttributes(attribute.String(key, v))
-	case int:
-		span.SetAttributes(attribute.Int(key, v))
-	case float64:
-		span.SetAttributes(attribute.Float64(key, v))
-	case bool:
-		span.SetAttributes(attribute.Bool(key, v))
-	default:
-		span.SetAttributes(attribute.String(key, fmt.Sprintf("%v", v)))
+	if !span.IsRecording() {
+		return
 	}
+
+	span.SetAttributes(t.limitAttributes([]attribute.KeyValue{ToAttribute(key, value)})...)
 }
 
 // AddEvent records an event with a name and optional attributes in the given span.
 func (t *tracing) AddEvent(ctx context.Context, span trace.Span, eventName string, opts ...trace.EventOption) {
-	span.AddEvent(eventName, opts...)
+	if !span.IsRecording() {
+		return
+	}
+	span.AddEvent(eventName, t.limitEventOptions(opts)...)
 }
 
 // SetOKStatus sets the status to OK with an optional description and attributes.
 func (t *tracing) SetOKStatus(span trace.Span, description string, attrs ...attribute.KeyValue) {
 	span.SetStatus(codes.Ok, description)
-	span.SetAttributes(attrs...)
+	span.SetAttributes(t.limitAttributes(attrs)...)
 }
 
-// SetNoContentStatus sets the status to indicate no content with an optional description and attributes.
+// SetNoContentStatus sets the status to OK and records a 204 No Content response, with an optional
+// description and attributes.
 func (t *tracing) SetNoContentStatus(span trace.Span, description string, attrs ...attribute.KeyValue) {
-	span.SetStatus(codes.Code(http.StatusNoContent), description)
-	span.SetAttributes(attrs...)
+	span.SetStatus(codes.Ok, description)
+	span.SetAttributes(semconv.HTTPResponseStatusCode(http.StatusNoContent))
+	span.SetAttributes(t.limitAttributes(attrs)...)
 }
 
 // GetTracer returns the underlying Tracer instance.
@@ -92,71 +185,80 @@ func (t *tracing) GetTracer() trace.Tracer {
 	return t.tracer
 }
 
-func (s *tracing) RecordError(ctx context.Context, span trace.Span, err *errs.ErrorService) {
-	s.SetStatus(span, codes.Error, err.Error())
-	s.AddAttributes(
-		span,
-		err,
-		semconv.ExceptionMessageKey.String(err.Error()),
-		semconv.ExceptionTypeKey.String(err.StatusText),
-		semconv.ExceptionStacktraceKey.String(err.GetStackTrace()),
-		attribute.Int("status.Code", 2),
-	)
-	span.AddEvent("ExceptionOccurred", trace.WithAttributes(
-		semconv.ExceptionTypeKey.String(err.StatusText),
-		semconv.ExceptionMessageKey.String(err.Error()),
-		attribute.String("exception.code", err.ErrorCode),
-		attribute.Int("status.Code", 2),
-	))
+// RecordError records err as an exception and sets the span status. When the chain holds an
+// *errs.ErrorService its HTTP status code is recorded and mapped to the span status with
+// SpanStatusFromHTTPStatus; any other error sets the Error status. Each error produces exactly one
+// "exception" event; errors joined with errors.Join produce one per joined error. Nothing is
+// recorded or logged for a non-recording span.
+func (s *tracing) RecordError(ctx context.Context, span trace.Span, err error) {
+	if err == nil || !span.IsRecording() {
+		return
+	}
+
+	if errService := asErrorService(err); errService != nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(errService.StatusCode))
+		if code, _ := SpanStatusFromHTTPStatus(errService.StatusCode, spanKind(span)); code != codes.Unset {
+			s.SetStatus(span, code, err.Error())
+		}
+	} else {
+		s.SetStatus(span, codes.Error, err.Error())
+	}
+
+	s.AddAttributes(span, err, exceptionSpanAttributes(err)...)
+	for _, leaf := range leafErrors(err) {
+		s.AddEvent(ctx, span, semconv.ExceptionEventName, trace.WithAttributes(exceptionAttributes(leaf)...))
+	}
 	if span.SpanContext().HasSpanID() {
-		s.l.WithContext(ctx).Errorf("%s", err.Error())
+		s.logger(ctx, span).Errorf("%s", err.Error())
 	}
-	span.RecordError(err)
 }
 
-func (s *tracing) AddErrorAttributes(ctx context.Context, span trace.Span, err *errs.ErrorService) {
-	if err.StatusCode >= 400 {
-		s.AddAttributes(
-			span,
-			err,
-			semconv.ExceptionMessageKey.String(err.Error()),
-			semconv.ExceptionTypeKey.String(err.StatusText),
-			semconv.ExceptionStacktraceKey.String(err.GetStackTrace()),
-		)
-		span.AddEvent("ExceptionOccurred", trace.WithAttributes(
-			semconv.ExceptionTypeKey.String(err.StatusText),
-			semconv.ExceptionMessageKey.String(err.Error()),
-			attribute.String("exception.code", err.ErrorCode),
-		))
+// logger returns a logger carrying span's trace and span IDs. The span itself is hidden from
+// the logger so that, with logs.WithSpanEvents, the error is not recorded a second time as a
+// "log" event next to its exception event.
+func (s *tracing) logger(ctx context.Context, span trace.Span) _logging.OtelLogging {
+	return s.l.WithContext(trace.ContextWithSpanContext(ctx, span.SpanContext()))
+}
+
+// AddErrorAttributes records err on the span without changing its status. An *errs.ErrorService
+// with a status code below 400 is recorded as a handled error; anything else as an exception.
+// Nothing is recorded or logged for a non-recording span.
+func (s *tracing) AddErrorAttributes(ctx context.Context, span trace.Span, err error) {
+	if err == nil || !span.IsRecording() {
+		return
+	}
+
+	if errService := asErrorService(err); errService == nil || errService.StatusCode >= 400 {
+		s.AddAttributes(span, err, exceptionSpanAttributes(err)...)
+		for _, leaf := range leafErrors(err) {
+			s.AddEvent(ctx, span, semconv.ExceptionEventName, trace.WithAttributes(exceptionAttributes(leaf)...))
+		}
 		if span.SpanContext().HasSpanID() {
-			s.l.WithContext(ctx).Errorf("[\n\nAEA\n\n]%s", err.Error())
+			s.logger(ctx, span).Errorf("%s", err.Error())
 		}
 	} else {
 		s.AddAttributes(
 			span,
 			err,
-			attribute.String("error.type", err.StatusText),
+			attribute.String("error.type", errorTypeName(err)),
 			attribute.String("error.message", err.Error()),
-			attribute.String("error.stacktrace", err.GetStackTrace()),
+			attribute.String("error.stacktrace", errService.GetStackTrace()),
 		)
-		span.AddEvent("ErrorHandled", trace.WithAttributes(
-			attribute.String("error.type", err.StatusText),
-			attribute.String("error.message", err.Error()),
-			attribute.String("error.code", err.ErrorCode),
-		))
+		for _, leaf := range leafErrors(err) {
+			s.AddEvent(ctx, span, "ErrorHandled", trace.WithAttributes(handledErrorAttributes(leaf)...))
+		}
 		if span.SpanContext().HasSpanID() {
-			s.l.WithContext(ctx).Warnf("%s", err.Error())
+			s.logger(ctx, span).Warnf("%s", err.Error())
 		}
 	}
-
 }
 
+// AddAttributes adds the given attributes to the span. The span status is not derived from err;
+// use RecordError or SetHTTPStatus for that.
 func (s *tracing) AddAttributes(span trace.Span, err error, attrs ...attribute.KeyValue) {
-	if err != nil {
-		span.SetAttributes(attrs...)
+	if !span.IsRecording() {
 		return
 	}
 
-	attrs = append(attrs, attribute.Int("status.Code", 1))
-	span.SetAttributes(attrs...)
+	span.SetAttributes(s.limitAttributes(attrs)...)
 }
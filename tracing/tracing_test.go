@@ -0,0 +1,106 @@
+package _tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"otel-library/errs"
+	apw_logging "otel-library/logs"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestErrorHelpersDoNotAllocateForNoopSpans(t *testing.T) {
+	tr := NewTracing(noop.NewTracerProvider().Tracer("test"), apw_logging.NewNoopLogging())
+	ctx, span := tr.StartSpan(context.Background(), "noop")
+	plain := errors.New("boom")
+	errService := errs.CreateNotFoundError(errs.NotFound, "missing")
+
+	tests := map[string]func(){
+		"RecordError":              func() { tr.RecordError(ctx, span, plain) },
+		"RecordError/ErrorService": func() { tr.RecordError(ctx, span, errService) },
+		"AddErrorAttributes":       func() { tr.AddErrorAttributes(ctx, span, plain) },
+	}
+	for name, fn := range tests {
+		t.Run(name, func(t *testing.T) {
+			if allocs := testing.AllocsPerRun(100, fn); allocs != 0 {
+				t.Errorf("got %v allocations per call, want 0", allocs)
+			}
+		})
+	}
+}
+
+func TestRecordErrorAddsOneExceptionEventPerError(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tr := NewTracing(tp.Tracer("test"), apw_logging.NewNoopLogging())
+
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"plain":        {err: errors.New("boom"), want: 1},
+		"ErrorService": {err: errs.CreateNotFoundError(errs.NotFound, "missing"), want: 1},
+		"joined":       {err: errors.Join(errors.New("a"), errors.New("b")), want: 2},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, span := tr.StartSpan(context.Background(), name)
+			tr.RecordError(ctx, span, tt.err)
+			span.End()
+
+			spans := recorder.Ended()
+			events := spans[len(spans)-1].Events()
+			if len(events) != tt.want {
+				t.Fatalf("got %d events, want %d: %v", len(events), tt.want, events)
+			}
+			for _, event := range events {
+				if event.Name != semconv.ExceptionEventName {
+					t.Errorf("event name = %q, want %q", event.Name, semconv.ExceptionEventName)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordErrorDoesNotMirrorLogAsSpanEvent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tr := NewTracing(tp.Tracer("test"), apw_logging.NewOtelLogging(apw_logging.WithSpanEvents()))
+
+	ctx, span := tr.StartSpan(context.Background(), "span-events")
+	tr.RecordError(ctx, span, errors.New("boom"))
+	span.End()
+
+	events := recorder.Ended()[0].Events()
+	if len(events) != 1 || events[0].Name != semconv.ExceptionEventName {
+		t.Fatalf("got events %v, want a single exception event", events)
+	}
+}
+
+func TestStartSpanDoesNotWriteIntoCallerOptions(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	tr := NewTracing(tp.Tracer("test"), apw_logging.NewNoopLogging(), WithBaggagePromotion("tenant.id"))
+	member, _ := baggage.NewMember("tenant.id", "acme")
+	bag, _ := baggage.New(member)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	opts := make([]trace.SpanStartOption, 1, 4)
+	opts[0] = trace.WithAttributes(attribute.String("caller", "value"))
+	spare := opts[:cap(opts)]
+
+	_, span := tr.StartSpanAuto(ctx, opts...)
+	span.End()
+	for i, opt := range spare[1:] {
+		if opt != nil {
+			t.Errorf("spare capacity at index %d was overwritten", i+1)
+		}
+	}
+}